@@ -13,6 +13,7 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
 
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/jwi078/rokku-operator/pkg/apis"
 	"github.com/jwi078/rokku-operator/pkg/controller"
 	"github.com/jwi078/rokku-operator/version"
@@ -122,6 +123,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Setup Scheme for the prometheus-operator resources owned by Rokku
+	if err := monitoringv1.AddToScheme(mgr.GetScheme()); err != nil {
+		log.Error(err, "")
+		os.Exit(1)
+	}
+
 	// Setup all Controllers
 	if err := controller.AddToManager(mgr); err != nil {
 		log.Error(err, "")
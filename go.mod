@@ -3,11 +3,13 @@ module github.com/jwi078/rokku-operator
 go 1.13
 
 require (
+	github.com/coreos/prometheus-operator v0.38.0
 	github.com/go-delve/delve v1.4.0 // indirect
 	github.com/howeyc/fsnotify v0.9.0 // indirect
 	github.com/operator-framework/operator-sdk v0.17.0
 	github.com/spf13/pflag v1.0.5
 	github.com/tsuru/config v0.0.0-20180418191556-87403ee7da02
+	golang.org/x/net v0.0.0-20200226121028-0de0cce0169b
 	k8s.io/api v0.17.4
 	k8s.io/apimachinery v0.17.4
 	k8s.io/client-go v12.0.0+incompatible
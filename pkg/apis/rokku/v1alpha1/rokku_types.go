@@ -2,8 +2,11 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -23,28 +26,674 @@ type RokkuSpec struct {
 	HealthcheckPath string                      `json:"healthcheckPath,omitempty"`
 	Resources       corev1.ResourceRequirements `json:"resources,omitempty"`
 	Environment     *RokkuEnvironment           `json:"env,omitempty"`
+	Backends        *RokkuBackends              `json:"backends,omitempty"`
+	NetworkPolicy   *RokkuNetworkPolicy         `json:"networkPolicy,omitempty"`
+	Monitoring      *RokkuMonitoring            `json:"monitoring,omitempty"`
+	Probes          *RokkuProbes                `json:"probes,omitempty"`
+	Rollout         *RokkuRollout               `json:"rollout,omitempty"`
+	// RevisionHistoryLimit is the number of ControllerRevisions kept with the
+	// previously applied specs. Defaults to 10.
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+	// RollbackTo is the number of the revision whose spec replaces the current
+	// one. It's cleared once the rollback is applied.
+	// +optional
+	RollbackTo *int64 `json:"rollbackTo,omitempty"`
+	// Paused stops the operator from changing the Rokku children, while still
+	// refreshing the status. Setting the rokku.ing.com/paused annotation to
+	// "true" has the same effect.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+	// Maintenance scales Rokku down and points the Service to a responder
+	// answering every request with an S3 SlowDown error.
+	// +optional
+	Maintenance *RokkuMaintenance `json:"maintenance,omitempty"`
+	// WorkloadKind is the kind of workload running the Rokku pods. Rollout
+	// strategies other than RollingUpdate only apply to Deployments. Defaults
+	// to WorkloadKindDeployment.
+	// +optional
+	WorkloadKind WorkloadKind `json:"workloadKind,omitempty"`
+	// InitContainers are run before the rokku container is started. They can
+	// mount the volumes declared in PodTemplate.Volumes.
+	// +optional
+	InitContainers []corev1.Container `json:"initContainers,omitempty"`
+	// Sidecars are run alongside the rokku container. They can mount the
+	// volumes declared in PodTemplate.Volumes.
+	// +optional
+	Sidecars []corev1.Container `json:"sidecars,omitempty"`
+	// Command overrides the entrypoint of the rokku image.
+	// +optional
+	Command []string `json:"command,omitempty"`
+	// Args are the arguments given to the entrypoint of the rokku image.
+	// +optional
+	Args []string `json:"args,omitempty"`
+	// JVM configures the JVM running Rokku through the JAVA_OPTS environment
+	// variable.
+	// +optional
+	JVM *RokkuJVM `json:"jvm,omitempty"`
+	// SecurityProfile hardens the security context of the pods generated by
+	// the operator. Violations of the restricted Pod Security Standard left by
+	// the user provided settings are reported in the status.
+	// +optional
+	SecurityProfile SecurityProfile `json:"securityProfile,omitempty"`
+	// ServiceAccount configures the ServiceAccount created for the Rokku pods.
+	// It's not created when PodTemplate.ServiceAccountName is set.
+	// +optional
+	ServiceAccount *RokkuServiceAccount `json:"serviceAccount,omitempty"`
+	// Trust adds CA certificates to the truststore of the Rokku JVM.
+	// +optional
+	Trust *RokkuTrust `json:"trust,omitempty"`
+	// Proxy configures the proxy Rokku reaches the backends through.
+	// +optional
+	Proxy *RokkuProxy `json:"proxy,omitempty"`
+	// STS configures the rokku-sts service Rokku authenticates requests with.
+	// +optional
+	STS *RokkuSTS `json:"sts,omitempty"`
+	// Overrides are patches applied to the objects generated by the
+	// operator, covering the fields Rokku doesn't model.
+	// +optional
+	Overrides *RokkuOverrides `json:"overrides,omitempty"`
+}
+
+// RokkuServiceAccount configures the ServiceAccount of the Rokku pods.
+type RokkuServiceAccount struct {
+	// Annotations are extra annotations for the ServiceAccount, e.g. the
+	// ones binding it to a cloud workload identity.
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+	// RoleRef references an existing Role or ClusterRole bound to the
+	// ServiceAccount in the Rokku namespace. No RoleBinding is created when
+	// neither RoleRef nor Rules are set. The operator must either hold the
+	// permissions of the role or be granted the bind verb on it.
+	// +optional
+	RoleRef *RokkuRoleRef `json:"roleRef,omitempty"`
+	// Rules are the permissions of a Role created in the Rokku namespace and
+	// bound to the ServiceAccount. It can't be set along with RoleRef. The
+	// operator must either hold these permissions or be granted the escalate
+	// verb on roles.
+	// +optional
+	Rules []rbacv1.PolicyRule `json:"rules,omitempty"`
+	// AutomountServiceAccountToken mounts the ServiceAccount token into the
+	// Rokku pods. Defaults to false.
+	// +optional
+	AutomountServiceAccountToken *bool `json:"automountServiceAccountToken,omitempty"`
 }
 
+// RokkuRoleRef references a Role or a ClusterRole.
+type RokkuRoleRef struct {
+	// Kind is either Role or ClusterRole. Defaults to Role.
+	// +optional
+	Kind string `json:"kind,omitempty"`
+	// Name of the Role or ClusterRole.
+	Name string `json:"name"`
+}
+
+// RokkuTrust configures the CA certificates trusted by Rokku, on top of the
+// ones shipped with the JVM.
+type RokkuTrust struct {
+	// CABundles are PEM encoded CA certificates, each of them holding one or
+	// more certificates. Pods are replaced whenever a bundle changes.
+	// +optional
+	CABundles []RokkuCABundle `json:"caBundles,omitempty"`
+}
+
+// RokkuCABundle references a key of a ConfigMap or a Secret holding a PEM CA
+// bundle. Exactly one of them must be set.
+type RokkuCABundle struct {
+	// +optional
+	ConfigMap *corev1.ConfigMapKeySelector `json:"configMap,omitempty"`
+	// +optional
+	Secret *corev1.SecretKeySelector `json:"secret,omitempty"`
+}
+
+// RokkuProxy configures the outbound HTTP proxy, rendered into both the
+// standard proxy environment variables and the JVM proxy properties.
+type RokkuProxy struct {
+	// HTTPProxy is the URL of the proxy used for HTTP requests, e.g.
+	// http://proxy:3128.
+	// +optional
+	HTTPProxy string `json:"httpProxy,omitempty"`
+	// HTTPSProxy is the URL of the proxy used for HTTPS requests.
+	// +optional
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	// NoProxy are the hosts, domains and CIDRs reached without the proxy.
+	// The cluster services, the service CIDR and the S3 backend are always
+	// added. The JVM doesn't support CIDRs, the IPv4 ones are rendered into
+	// wildcard patterns and the IPv6 ones are dropped.
+	// +optional
+	NoProxy []string `json:"noProxy,omitempty"`
+	// ServiceCIDR is the CIDR of the cluster services. Defaults to the
+	// SERVICE_CIDR environment variable of the operator, else to the /16
+	// holding the ClusterIP of the kubernetes Service. The latter is only a
+	// guess which may not match the service CIDR, which must then be set
+	// explicitly.
+	// +optional
+	ServiceCIDR string `json:"serviceCIDR,omitempty"`
+}
+
+// RokkuSTS configures the rokku-sts service.
+type RokkuSTS struct {
+	// Managed deploys a rokku-sts owned by the Rokku, whose URI is used as
+	// Backends.STS, which can't be set then. Rokku isn't rolled out for the
+	// first time until it's ready.
+	// +optional
+	Managed *RokkuManagedSTS `json:"managed,omitempty"`
+	// OIDC configures the Keycloak realm of the managed rokku-sts.
+	// +optional
+	OIDC *RokkuOIDC `json:"oidc,omitempty"`
+}
+
+// RokkuManagedSTS is the rokku-sts deployed by the operator.
+type RokkuManagedSTS struct {
+	// Image is the rokku-sts image. Defaults to wbaa/rokku-sts.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Replicas is the number of rokku-sts pods. Defaults to 1.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Port is the port rokku-sts listens on. Defaults to 12345.
+	// +optional
+	Port int32 `json:"port,omitempty"`
+	// DatabaseSecret is the Secret holding the MariaDB connection of
+	// rokku-sts, in its url, username and password keys.
+	// +optional
+	DatabaseSecret *corev1.LocalObjectReference `json:"databaseSecret,omitempty"`
+	// MasterKeySecret is the Secret holding the key rokku-sts encrypts the
+	// user secrets with, in its masterKey key.
+	// +optional
+	MasterKeySecret *corev1.LocalObjectReference `json:"masterKeySecret,omitempty"`
+	// Resources of the rokku-sts container.
+	// +optional
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// Env are extra environment variables of the rokku-sts container.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+}
+
+// RokkuOIDC is the Keycloak realm rokku-sts verifies the tokens against.
+type RokkuOIDC struct {
+	// IssuerURL is the issuer of the tokens, e.g.
+	// https://keycloak:8443/auth/realms/auth-rokku. Its discovery document is
+	// checked at reconcile time, see the OIDCReachable condition.
+	IssuerURL string `json:"issuerURL"`
+	// Realm the tokens are issued by. Defaults to the realm of IssuerURL.
+	// +optional
+	Realm string `json:"realm,omitempty"`
+	// ClientID is the client rokku-sts authenticates as.
+	// +optional
+	ClientID string `json:"clientID,omitempty"`
+	// ClientSecret references the secret of the client.
+	// +optional
+	ClientSecret *corev1.SecretKeySelector `json:"clientSecret,omitempty"`
+	// VerifyToken configures how rokku-sts verifies the tokens.
+	// +optional
+	VerifyToken *RokkuOIDCVerifyToken `json:"verifyToken,omitempty"`
+}
+
+// RokkuOIDCVerifyToken configures the token verification of rokku-sts.
+type RokkuOIDCVerifyToken struct {
+	// PublicKeyID is the id of the realm key the tokens are signed with.
+	// +optional
+	PublicKeyID string `json:"publicKeyId,omitempty"`
+	// CheckRealmURL checks that the tokens were issued by the realm.
+	// Defaults to true.
+	// +optional
+	CheckRealmURL *bool `json:"checkRealmURL,omitempty"`
+	// IssuerForList are the clients whose tokens are accepted.
+	// +optional
+	IssuerForList []string `json:"issuerForList,omitempty"`
+}
+
+type SecurityProfile string
+
+const (
+	// SecurityProfileRestricted runs the Rokku container as non-root with a
+	// read-only root filesystem, no capabilities other than NET_BIND_SERVICE
+	// and the runtime default seccomp profile. The seccomp profile is set
+	// through the seccomp.security.alpha.kubernetes.io/pod annotation, which
+	// the API server copies into the pod securityContext from Kubernetes 1.19.
+	SecurityProfileRestricted = SecurityProfile("Restricted")
+)
+
+// RokkuJVM are the options of the JVM running Rokku. None of the options,
+// including the system property values, may contain whitespace, as JAVA_OPTS
+// is split on whitespace.
+type RokkuJVM struct {
+	// HeapPercentage is the max heap size as a percentage of the container
+	// memory limit, rendered into -XX:MaxRAMPercentage.
+	// +optional
+	HeapPercentage *int32 `json:"heapPercentage,omitempty"`
+	// GCOptions are the garbage collector options, e.g. -XX:+UseG1GC.
+	// +optional
+	GCOptions []string `json:"gcOptions,omitempty"`
+	// SystemProperties are rendered into -D options.
+	// +optional
+	SystemProperties map[string]string `json:"systemProperties,omitempty"`
+	// ExtraOptions are appended as-is to the JVM options.
+	// +optional
+	ExtraOptions []string `json:"extraOptions,omitempty"`
+}
+
+// RokkuOverrides are strategic merge patches applied to the generated
+// objects. Patches can't change the name, namespace, owner references or
+// selector of the objects.
+type RokkuOverrides struct {
+	// Deployment is applied to the Deployments, and to the pod template of
+	// the DaemonSet, running Rokku.
+	// +optional
+	Deployment *runtime.RawExtension `json:"deployment,omitempty"`
+	// Service is applied to the Service exposing Rokku.
+	// +optional
+	Service *runtime.RawExtension `json:"service,omitempty"`
+}
+
+type WorkloadKind string
+
+const (
+	// WorkloadKindDeployment runs the configured number of Rokku replicas.
+	WorkloadKindDeployment = WorkloadKind("Deployment")
+	// WorkloadKindDaemonSet runs a Rokku pod on every node matching the pod
+	// template node selector, typically combined with HostNetwork.
+	WorkloadKindDaemonSet = WorkloadKind("DaemonSet")
+)
+
 type RokkuEnvironment struct {
 	EnvName  string `json:"name,omitempty"`
 	EnvValue string `json:"value,omitempty"`
 }
 
+// RokkuBackends holds the endpoints of the services Rokku talks to.
+type RokkuBackends struct {
+	// S3 is the URL of the S3 backend, e.g. http://ceph-server:1234.
+	// +optional
+	S3 string `json:"s3,omitempty"`
+	// STS is the URL of the rokku-sts service. Defaults to http://rokku-sts:8080.
+	// +optional
+	STS string `json:"sts,omitempty"`
+	// RangerAdmin is the URL of the Ranger admin the policies are fetched from.
+	// +optional
+	RangerAdmin string `json:"rangerAdmin,omitempty"`
+	// Atlas is the URL of the Atlas API used for lineage.
+	// +optional
+	Atlas string `json:"atlas,omitempty"`
+	// Kafka is the list of Kafka bootstrap servers in host:port form.
+	// +optional
+	Kafka []string `json:"kafka,omitempty"`
+}
+
+// RokkuNetworkPolicy configures the NetworkPolicy created for a Rokku. Egress
+// rules towards the Backends are derived automatically.
+type RokkuNetworkPolicy struct {
+	// AllowedClients are the peers allowed to reach the Rokku ports. Defaults
+	// to any peer.
+	// +optional
+	AllowedClients []networkingv1.NetworkPolicyPeer `json:"allowedClients,omitempty"`
+	// MetricsClients are the peers allowed to scrape the metrics port when
+	// Monitoring is enabled, e.g. the Prometheus pods. Defaults to any peer.
+	// +optional
+	MetricsClients []networkingv1.NetworkPolicyPeer `json:"metricsClients,omitempty"`
+	// ExtraEgress are egress rules appended to the derived ones.
+	// +optional
+	ExtraEgress []networkingv1.NetworkPolicyEgressRule `json:"extraEgress,omitempty"`
+}
+
+// RokkuMonitoring configures how Prometheus scrapes the Rokku metrics.
+type RokkuMonitoring struct {
+	// Port is the container port serving the Rokku/JVM metrics. Defaults to 9404.
+	// +optional
+	Port int32 `json:"port,omitempty"`
+	// Path is the HTTP path the metrics are served at. Defaults to /metrics.
+	// +optional
+	Path string `json:"path,omitempty"`
+	// Interval at which the metrics are scraped, e.g. 30s. Defaults to the
+	// Prometheus scrape interval.
+	// +optional
+	Interval string `json:"interval,omitempty"`
+	// MonitorKind is the kind of monitor created for Rokku. Defaults to
+	// MonitorKindServiceMonitor.
+	// +optional
+	MonitorKind MonitorKind `json:"monitorKind,omitempty"`
+	// Labels are extra labels for the monitor and the PrometheusRule, used by
+	// Prometheus to select them.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Alerts enables a PrometheusRule with the standard Rokku alerts.
+	// +optional
+	Alerts *RokkuAlerts `json:"alerts,omitempty"`
+}
+
+// RokkuAlerts configures the standard Rokku alerts. Each alert can be tuned or
+// disabled individually.
+type RokkuAlerts struct {
+	// PodsNotReady fires when fewer Rokku pods than desired are available.
+	// Threshold is the number of unavailable pods tolerated, defaults to 0.
+	// +optional
+	PodsNotReady *RokkuAlert `json:"podsNotReady,omitempty"`
+	// HighErrorRate fires when the ratio of 5xx responses is above Threshold,
+	// defaults to 0.05.
+	// +optional
+	HighErrorRate *RokkuAlert `json:"highErrorRate,omitempty"`
+	// STSUnreachable fires when the rate of failed STS calls per second is
+	// above Threshold, defaults to 0.
+	// +optional
+	STSUnreachable *RokkuAlert `json:"stsUnreachable,omitempty"`
+	// RangerPolicyRefreshFailing fires when the Ranger policies have not been
+	// refreshed for Threshold seconds, defaults to 600.
+	// +optional
+	RangerPolicyRefreshFailing *RokkuAlert `json:"rangerPolicyRefreshFailing,omitempty"`
+	// Labels are extra labels added to every alert.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// RokkuAlert overrides the defaults of a standard alert.
+type RokkuAlert struct {
+	// Disabled removes the alert from the PrometheusRule.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+	// Threshold is the value the alert expression is compared against. It must
+	// be a number.
+	// +optional
+	Threshold string `json:"threshold,omitempty"`
+	// For is how long the condition must hold before the alert fires, e.g. 5m.
+	// +optional
+	For string `json:"for,omitempty"`
+	// Severity is the value of the severity label of the alert.
+	// +optional
+	Severity string `json:"severity,omitempty"`
+}
+
+type MonitorKind string
+
+const (
+	// MonitorKindServiceMonitor scrapes Rokku through its Service.
+	MonitorKindServiceMonitor = MonitorKind("ServiceMonitor")
+	// MonitorKindPodMonitor scrapes the Rokku pods directly.
+	MonitorKindPodMonitor = MonitorKind("PodMonitor")
+)
+
 // RokkuProxyStatus defines the observed state of RokkuProxy
 type RokkuStatus struct {
+	// Pods holds the status of each Rokku pod. It's left empty when there are
+	// more pods than fit the status, see PodsSummary.
 	Pods            []PodStatus     `json:"pods,omitempty"`
+	PodsSummary     *PodsSummary    `json:"podsSummary,omitempty"`
 	Services        []ServiceStatus `json:"services,omitempty"`
 	CurrentReplicas int32           `json:"currentReplicas,omitempty"`
 	PodSelector     string          `json:"podSelector,omitempty"`
+	// Endpoint is the URL S3 clients should use to reach Rokku
+	Endpoint string `json:"endpoint,omitempty"`
+	// Rollout is the progress of the current rollout, if any
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+	// CurrentRevision is the ControllerRevision of the last fully rolled out spec
+	CurrentRevision string `json:"currentRevision,omitempty"`
+	// UpdateRevision is the ControllerRevision of the spec being applied
+	UpdateRevision string `json:"updateRevision,omitempty"`
+	// Workload is the status of the workload running the Rokku pods
+	Workload *WorkloadStatus `json:"workload,omitempty"`
+	// SecurityViolations are the violations of the restricted Pod Security
+	// Standard found in the pod template when using the Restricted security
+	// profile
+	SecurityViolations []string `json:"securityViolations,omitempty"`
+	// ActiveColor is the color of the Deployment selected by the Service when
+	// using the BlueGreen strategy. The Service records it in its
+	// rokku.ing.com/active-color annotation, which takes precedence
+	ActiveColor string `json:"activeColor,omitempty"`
+	// Conditions are the latest observations of the Rokku state
+	Conditions []RokkuCondition `json:"conditions,omitempty"`
+}
+
+type WorkloadStatus struct {
+	// Kind of the workload, Deployment or DaemonSet
+	Kind WorkloadKind `json:"kind"`
+	// Replicas is the number of desired pods
+	Replicas int32 `json:"replicas"`
+	// ReadyReplicas is the number of ready pods
+	ReadyReplicas int32 `json:"readyReplicas"`
+	// UpdatedReplicas is the number of pods running the latest spec
+	UpdatedReplicas int32 `json:"updatedReplicas"`
+	// AvailableReplicas is the number of available pods
+	AvailableReplicas int32 `json:"availableReplicas"`
+}
+
+type RokkuConditionType string
+
+const (
+	// RokkuProgressing tells whether a rollout is in progress and how it ended.
+	RokkuProgressing = RokkuConditionType("Progressing")
+	// RokkuDegraded tells whether the Rokku Deployment failed to roll out.
+	RokkuDegraded = RokkuConditionType("Degraded")
+	// RokkuPaused tells whether the reconciliation of the Rokku is paused.
+	RokkuPaused = RokkuConditionType("Paused")
+	// RokkuSTSReady tells whether the managed rokku-sts is ready.
+	RokkuSTSReady = RokkuConditionType("STSReady")
+	// RokkuOIDCReachable tells whether the discovery document of the OIDC
+	// issuer could be fetched and matches the issuer.
+	RokkuOIDCReachable = RokkuConditionType("OIDCReachable")
+	// RokkuValid tells whether the Rokku spec is valid. Nothing is rolled out
+	// while it's invalid.
+	RokkuValid = RokkuConditionType("Valid")
+	// RokkuConfigAvailable tells whether the config ConfigMap exists. The
+	// pods can't start without it.
+	RokkuConfigAvailable = RokkuConditionType("ConfigAvailable")
+	// RokkuCABundlesAvailable tells whether the CA bundles which aren't
+	// optional exist. The pods can't start without them.
+	RokkuCABundlesAvailable = RokkuConditionType("CABundlesAvailable")
+	// RokkuServiceAccountReady tells whether the ServiceAccount, Role and
+	// RoleBinding generated for the Rokku pods are in place. Objects of the
+	// same name not controlled by the Rokku are left alone and reported.
+	RokkuServiceAccountReady = RokkuConditionType("ServiceAccountReady")
+)
+
+type RokkuCondition struct {
+	// Type of the condition
+	Type RokkuConditionType `json:"type"`
+	// Status of the condition, one of True, False or Unknown
+	Status corev1.ConditionStatus `json:"status"`
+	// Reason is a CamelCase reason for the last transition
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Message is a human readable explanation of the last transition
+	// +optional
+	Message string `json:"message,omitempty"`
+	// LastTransitionTime is the last time the status changed
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+type RolloutPhase string
+
+const (
+	// RolloutProgressing means the new spec is being rolled out.
+	RolloutProgressing = RolloutPhase("Progressing")
+	// RolloutPromoted means the new spec was fully rolled out.
+	RolloutPromoted = RolloutPhase("Promoted")
+	// RolloutAborted means the rollout failed one of its gates and the
+	// previous spec was kept.
+	RolloutAborted = RolloutPhase("Aborted")
+)
+
+type RolloutStatus struct {
+	// Phase of the rollout
+	Phase RolloutPhase `json:"phase"`
+	// SpecHash identifies the Rokku spec being rolled out
+	SpecHash string `json:"specHash"`
+	// Step is the index of the current canary step
+	// +optional
+	Step int32 `json:"step,omitempty"`
+	// StepStartTime is the time the current step started
+	// +optional
+	StepStartTime *metav1.Time `json:"stepStartTime,omitempty"`
+	// Message is a human readable description of the rollout progress
+	// +optional
+	Message string `json:"message,omitempty"`
+	// CompletionTime is the time the rollout was promoted
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// RokkuMaintenance configures the maintenance mode. The number of replicas
+// from the spec is restored once it's disabled.
+type RokkuMaintenance struct {
+	// Enabled puts Rokku in maintenance.
+	Enabled bool `json:"enabled"`
+	// Replicas is the number of Rokku replicas kept running during the
+	// maintenance. Defaults to 0. Ignored by the DaemonSet workload kind,
+	// whose pods are all removed during the maintenance.
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Image of the maintenance responder, which must be nginx based.
+	// Defaults to nginxinc/nginx-unprivileged:stable-alpine.
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Message is the message of the S3 error returned to the clients.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// RokkuHighAvailability configures how the rokku pods are spread when running
+// more than one replica, so that a single node or zone failure doesn't take
+// down every pod. The defaults are preferences only and are never injected
+// over a pod anti-affinity or topology spread constraints set in the pod
+// template.
+type RokkuHighAvailability struct {
+	// Disabled disables the high-availability scheduling defaults.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+	// NodeTopologyKey is the node label the pods are spread across by the
+	// default pod anti-affinity. Defaults to kubernetes.io/hostname.
+	// +optional
+	NodeTopologyKey string `json:"nodeTopologyKey,omitempty"`
+	// ZoneTopologyKey is the node label the pods are spread across by the
+	// default topology spread constraint. Defaults to
+	// topology.kubernetes.io/zone.
+	// +optional
+	ZoneTopologyKey string `json:"zoneTopologyKey,omitempty"`
+	// MaxSkew is the max difference of pods between two zones. Defaults to 1.
+	// +optional
+	MaxSkew *int32 `json:"maxSkew,omitempty"`
+}
+
+// RokkuProbes configures the probes of the Rokku container. Probes default
+// to an HTTP GET against HealthcheckPath.
+type RokkuProbes struct {
+	// Scheme used by the default HTTP probes. When set to HTTPS the https port
+	// is probed instead of the http one. Defaults to HTTP.
+	// +optional
+	Scheme corev1.URIScheme `json:"scheme,omitempty"`
+	// Liveness overrides the default liveness probe. Its successThreshold
+	// must be 1.
+	// +optional
+	Liveness *RokkuProbe `json:"liveness,omitempty"`
+	// Readiness overrides the default readiness probe.
+	// +optional
+	Readiness *RokkuProbe `json:"readiness,omitempty"`
+	// Startup overrides the default startup probe. Its successThreshold must
+	// be 1.
+	// +optional
+	Startup *RokkuProbe `json:"startup,omitempty"`
+}
+
+// RokkuProbe overrides a probe of the Rokku container. The default handler is
+// kept unless one of exec, httpGet or tcpSocket is set, and only the non-zero
+// thresholds and delays replace the default ones.
+type RokkuProbe struct {
+	// Disabled removes the probe from the Rokku container.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+
+	corev1.Probe `json:",inline"`
+}
+
+// RokkuRollout configures how spec changes are rolled out.
+type RokkuRollout struct {
+	// Strategy used to roll out spec changes. Defaults to
+	// RolloutStrategyRollingUpdate.
+	// +optional
+	Strategy RolloutStrategy `json:"strategy,omitempty"`
+	// Canary configures the Canary strategy.
+	// +optional
+	Canary *RokkuCanary `json:"canary,omitempty"`
+	// BlueGreen configures the BlueGreen strategy.
+	// +optional
+	BlueGreen *RokkuBlueGreen `json:"blueGreen,omitempty"`
+	// ProgressDeadlineSeconds is the max time the new pods may take to become
+	// ready before the rollout is considered failed. Defaults to 600.
+	// +optional
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+	// AutoRollback restores the last spec that was fully rolled out when the
+	// RollingUpdate strategy exceeds its progress deadline.
+	// +optional
+	AutoRollback bool `json:"autoRollback,omitempty"`
+}
+
+type RolloutStrategy string
+
+const (
+	// RolloutStrategyRollingUpdate updates the Rokku Deployment in place.
+	RolloutStrategyRollingUpdate = RolloutStrategy("RollingUpdate")
+	// RolloutStrategyCanary moves the replicas to a canary Deployment step by
+	// step, promoting it once all the steps pass their gates.
+	RolloutStrategyCanary = RolloutStrategy("Canary")
+	// RolloutStrategyBlueGreen brings up a full Deployment of the other color
+	// and switches the Service to it once it's available. The first time, the
+	// Service is first switched to a color running the current spec.
+	RolloutStrategyBlueGreen = RolloutStrategy("BlueGreen")
+)
+
+type RokkuBlueGreen struct {
+	// RollbackWindowSeconds is how long the previous color is kept after the
+	// Service is switched, so that reverting the spec switches back right
+	// away. Defaults to 3600.
+	// +optional
+	RollbackWindowSeconds *int32 `json:"rollbackWindowSeconds,omitempty"`
+}
+
+type RokkuCanary struct {
+	// Steps are the percentages of the replicas served by the canary at each
+	// step. Defaults to 25 and 50.
+	// +optional
+	Steps []int32 `json:"steps,omitempty"`
+	// PauseSeconds is how long each step is observed before moving to the
+	// next one. Defaults to 60.
+	// +optional
+	PauseSeconds *int32 `json:"pauseSeconds,omitempty"`
+	// Analysis configures the error-rate gate checked before each step.
+	// +optional
+	Analysis *RokkuCanaryAnalysis `json:"analysis,omitempty"`
+}
+
+type RokkuCanaryAnalysis struct {
+	// PrometheusURL is the address of the Prometheus holding the Rokku
+	// metrics, e.g. http://prometheus-operated.monitoring:9090.
+	PrometheusURL string `json:"prometheusURL"`
+	// MaxErrorRate is the max ratio of 5xx responses served by the canary.
+	// Must be between 0 and 1, defaults to 0.05.
+	// +optional
+	MaxErrorRate string `json:"maxErrorRate,omitempty"`
 }
 
 type RokkuLifecycle struct {
 	PostStart *RokkuLifecycleHandler `json:"postStart,omitempty"`
-	PreStop   *RokkuLifecycleHandler `json:"preStop,omitempty"`
+	// PreStop defaults to sleeping half of the termination grace period,
+	// leaving time for the pod to be removed from the load balancers before
+	// Rokku stops accepting connections.
+	// +optional
+	PreStop *RokkuLifecycleHandler `json:"preStop,omitempty"`
 }
 
+// RokkuLifecycleHandler is the action taken by a lifecycle hook. Exactly one
+// of the actions must be set.
 type RokkuLifecycleHandler struct {
+	// Exec runs the command as-is, without a shell.
+	// +optional
 	Exec *corev1.ExecAction `json:"exec,omitempty"`
+	// HTTPGet sends a GET request to the rokku container.
+	// +optional
+	HTTPGet *corev1.HTTPGetAction `json:"httpGet,omitempty"`
+	// TCPSocket opens a connection to the rokku container.
+	// +optional
+	TCPSocket *corev1.TCPSocketAction `json:"tcpSocket,omitempty"`
 }
 
 type RokkuService struct {
@@ -113,9 +762,45 @@ type RokkuPodTemplateSpec struct {
 	// Labels are custom labels to be added into Pod.
 	// +optional
 	Labels map[string]string `json:"labels,omitempty"`
+	// NodeSelector restricts the nodes the pod can be scheduled to.
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
 	// HostNetwork enabled causes the pod to use the host's network namespace.
 	// +optional
 	HostNetwork bool `json:"hostNetwork,omitempty"`
+	// Tolerations allow the pod to be scheduled onto nodes with matching taints.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+	// TopologySpreadConstraints describes how the rokku pods spread across
+	// topology domains.
+	// +optional
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+	// HighAvailability configures the default anti-affinity and topology
+	// spread injected when running more than one replica.
+	// +optional
+	HighAvailability *RokkuHighAvailability `json:"highAvailability,omitempty"`
+	// PriorityClassName is the priority class of the rokku pod.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+	// ImagePullSecrets are the secrets used to pull the rokku image.
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+	// ServiceAccountName is the service account the rokku pod runs as.
+	// +optional
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+	// DNSPolicy of the rokku pod. Defaults to ClusterFirstWithHostNet when
+	// HostNetwork is enabled, otherwise to the pod's dnsPolicy default value.
+	// +optional
+	DNSPolicy corev1.DNSPolicy `json:"dnsPolicy,omitempty"`
+	// DNSConfig specifies the DNS parameters of the rokku pod.
+	// +optional
+	DNSConfig *corev1.PodDNSConfig `json:"dnsConfig,omitempty"`
+	// HostAliases are entries added to the pod's hosts file.
+	// +optional
+	HostAliases []corev1.HostAlias `json:"hostAliases,omitempty"`
+	// RuntimeClassName is the runtime class used to run the rokku pod.
+	// +optional
+	RuntimeClassName *string `json:"runtimeClassName,omitempty"`
 	// Ports is the list of ports used by Rokku.
 	// +optional
 	Ports []corev1.ContainerPort `json:"ports,omitempty"`
@@ -166,11 +851,73 @@ type PodStatus struct {
 	PodIP string `json:"podIP"`
 	// HostIP is the IP where POD is running
 	HostIP string `json:"hostIP"`
+	// Phase is the current phase of the POD
+	Phase corev1.PodPhase `json:"phase,omitempty"`
+	// Ready is whether the POD is ready to serve requests
+	Ready bool `json:"ready"`
+	// RestartCount is the number of times the rokku container has restarted
+	RestartCount int32 `json:"restartCount"`
+	// LastTerminationReason is the reason the rokku container last terminated
+	// +optional
+	LastTerminationReason string `json:"lastTerminationReason,omitempty"`
+	// Image is the image the rokku container is running
+	// +optional
+	Image string `json:"image,omitempty"`
+	// ImageID is the image digest the rokku container is running
+	// +optional
+	ImageID string `json:"imageID,omitempty"`
+	// NodeName is the name of the node the POD is scheduled to
+	// +optional
+	NodeName string `json:"nodeName,omitempty"`
+	// StartTime is the time the POD was started by the kubelet
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// ConfigChecksum is the checksum of the configuration the POD was created with
+	// +optional
+	ConfigChecksum string `json:"configChecksum,omitempty"`
+}
+
+// PodsSummary aggregates the status of all the Rokku pods.
+type PodsSummary struct {
+	// Total is the number of pods
+	Total int32 `json:"total"`
+	// Ready is the number of ready pods
+	Ready int32 `json:"ready"`
+	// Restarts is the sum of the restarts of the rokku containers
+	Restarts int32 `json:"restarts"`
+	// Phases is the number of pods in each phase
+	// +optional
+	Phases map[corev1.PodPhase]int32 `json:"phases,omitempty"`
 }
 
 type ServiceStatus struct {
 	// Name is the name of the Service created by rokku
 	Name string `json:"name"`
+	// DNSName is the name the Service resolves to inside the cluster
+	// +optional
+	DNSName string `json:"dnsName,omitempty"`
+	// ClusterIP is the cluster IP of the Service
+	// +optional
+	ClusterIP string `json:"clusterIP,omitempty"`
+	// Ports are the ports exposed by the Service
+	// +optional
+	Ports []ServicePortStatus `json:"ports,omitempty"`
+	// LoadBalancerIngress are the IPs or hostnames of the load balancer
+	// +optional
+	LoadBalancerIngress []string `json:"loadBalancerIngress,omitempty"`
+	// Hosts are the hosts of the Ingresses and Routes pointing to the Service
+	// +optional
+	Hosts []string `json:"hosts,omitempty"`
+}
+
+type ServicePortStatus struct {
+	// Name is the name of the port
+	Name string `json:"name"`
+	// Port is the port exposed by the Service
+	Port int32 `json:"port"`
+	// NodePort is the port exposed on each node, if any
+	// +optional
+	NodePort int32 `json:"nodePort,omitempty"`
 }
 
 // FilesRef is a reference to arbitrary files stored into a ConfigMap in the
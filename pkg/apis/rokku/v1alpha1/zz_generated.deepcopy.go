@@ -6,6 +6,8 @@ package v1alpha1
 
 import (
 	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -51,6 +53,10 @@ func (in *FilesRef) DeepCopy() *FilesRef {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PodStatus) DeepCopyInto(out *PodStatus) {
 	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -64,6 +70,29 @@ func (in *PodStatus) DeepCopy() *PodStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodsSummary) DeepCopyInto(out *PodsSummary) {
+	*out = *in
+	if in.Phases != nil {
+		in, out := &in.Phases, &out.Phases
+		*out = make(map[v1.PodPhase]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodsSummary.
+func (in *PodsSummary) DeepCopy() *PodsSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(PodsSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Rokku) DeepCopyInto(out *Rokku) {
 	*out = *in
@@ -92,6 +121,197 @@ func (in *Rokku) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuAlert) DeepCopyInto(out *RokkuAlert) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuAlert.
+func (in *RokkuAlert) DeepCopy() *RokkuAlert {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuAlert)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuAlerts) DeepCopyInto(out *RokkuAlerts) {
+	*out = *in
+	if in.PodsNotReady != nil {
+		in, out := &in.PodsNotReady, &out.PodsNotReady
+		*out = new(RokkuAlert)
+		**out = **in
+	}
+	if in.HighErrorRate != nil {
+		in, out := &in.HighErrorRate, &out.HighErrorRate
+		*out = new(RokkuAlert)
+		**out = **in
+	}
+	if in.STSUnreachable != nil {
+		in, out := &in.STSUnreachable, &out.STSUnreachable
+		*out = new(RokkuAlert)
+		**out = **in
+	}
+	if in.RangerPolicyRefreshFailing != nil {
+		in, out := &in.RangerPolicyRefreshFailing, &out.RangerPolicyRefreshFailing
+		*out = new(RokkuAlert)
+		**out = **in
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuAlerts.
+func (in *RokkuAlerts) DeepCopy() *RokkuAlerts {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuAlerts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuBackends) DeepCopyInto(out *RokkuBackends) {
+	*out = *in
+	if in.Kafka != nil {
+		in, out := &in.Kafka, &out.Kafka
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuBackends.
+func (in *RokkuBackends) DeepCopy() *RokkuBackends {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuBackends)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuBlueGreen) DeepCopyInto(out *RokkuBlueGreen) {
+	*out = *in
+	if in.RollbackWindowSeconds != nil {
+		in, out := &in.RollbackWindowSeconds, &out.RollbackWindowSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuBlueGreen.
+func (in *RokkuBlueGreen) DeepCopy() *RokkuBlueGreen {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuBlueGreen)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuCABundle) DeepCopyInto(out *RokkuCABundle) {
+	*out = *in
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(v1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Secret != nil {
+		in, out := &in.Secret, &out.Secret
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuCABundle.
+func (in *RokkuCABundle) DeepCopy() *RokkuCABundle {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuCABundle)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuCanary) DeepCopyInto(out *RokkuCanary) {
+	*out = *in
+	if in.Steps != nil {
+		in, out := &in.Steps, &out.Steps
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+	if in.PauseSeconds != nil {
+		in, out := &in.PauseSeconds, &out.PauseSeconds
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Analysis != nil {
+		in, out := &in.Analysis, &out.Analysis
+		*out = new(RokkuCanaryAnalysis)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuCanary.
+func (in *RokkuCanary) DeepCopy() *RokkuCanary {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuCanary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuCanaryAnalysis) DeepCopyInto(out *RokkuCanaryAnalysis) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuCanaryAnalysis.
+func (in *RokkuCanaryAnalysis) DeepCopy() *RokkuCanaryAnalysis {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuCanaryAnalysis)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuCondition) DeepCopyInto(out *RokkuCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuCondition.
+func (in *RokkuCondition) DeepCopy() *RokkuCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RokkuConfigSpec) DeepCopyInto(out *RokkuConfigSpec) {
 	*out = *in
@@ -114,161 +334,623 @@ func (in *RokkuConfigSpec) DeepCopy() *RokkuConfigSpec {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RokkuEnvironment) DeepCopyInto(out *RokkuEnvironment) {
+func (in *RokkuEnvironment) DeepCopyInto(out *RokkuEnvironment) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuEnvironment.
+func (in *RokkuEnvironment) DeepCopy() *RokkuEnvironment {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuEnvironment)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuHighAvailability) DeepCopyInto(out *RokkuHighAvailability) {
+	*out = *in
+	if in.MaxSkew != nil {
+		in, out := &in.MaxSkew, &out.MaxSkew
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuHighAvailability.
+func (in *RokkuHighAvailability) DeepCopy() *RokkuHighAvailability {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuHighAvailability)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuJVM) DeepCopyInto(out *RokkuJVM) {
+	*out = *in
+	if in.HeapPercentage != nil {
+		in, out := &in.HeapPercentage, &out.HeapPercentage
+		*out = new(int32)
+		**out = **in
+	}
+	if in.GCOptions != nil {
+		in, out := &in.GCOptions, &out.GCOptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SystemProperties != nil {
+		in, out := &in.SystemProperties, &out.SystemProperties
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ExtraOptions != nil {
+		in, out := &in.ExtraOptions, &out.ExtraOptions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuJVM.
+func (in *RokkuJVM) DeepCopy() *RokkuJVM {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuJVM)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuLifecycle) DeepCopyInto(out *RokkuLifecycle) {
+	*out = *in
+	if in.PostStart != nil {
+		in, out := &in.PostStart, &out.PostStart
+		*out = new(RokkuLifecycleHandler)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PreStop != nil {
+		in, out := &in.PreStop, &out.PreStop
+		*out = new(RokkuLifecycleHandler)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuLifecycle.
+func (in *RokkuLifecycle) DeepCopy() *RokkuLifecycle {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuLifecycle)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuLifecycleHandler) DeepCopyInto(out *RokkuLifecycleHandler) {
+	*out = *in
+	if in.Exec != nil {
+		in, out := &in.Exec, &out.Exec
+		*out = new(v1.ExecAction)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HTTPGet != nil {
+		in, out := &in.HTTPGet, &out.HTTPGet
+		*out = new(v1.HTTPGetAction)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TCPSocket != nil {
+		in, out := &in.TCPSocket, &out.TCPSocket
+		*out = new(v1.TCPSocketAction)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuLifecycleHandler.
+func (in *RokkuLifecycleHandler) DeepCopy() *RokkuLifecycleHandler {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuLifecycleHandler)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuList) DeepCopyInto(out *RokkuList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Rokku, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuList.
+func (in *RokkuList) DeepCopy() *RokkuList {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *RokkuList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuMaintenance) DeepCopyInto(out *RokkuMaintenance) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuMaintenance.
+func (in *RokkuMaintenance) DeepCopy() *RokkuMaintenance {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuMaintenance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuManagedSTS) DeepCopyInto(out *RokkuManagedSTS) {
+	*out = *in
+	if in.Replicas != nil {
+		in, out := &in.Replicas, &out.Replicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.DatabaseSecret != nil {
+		in, out := &in.DatabaseSecret, &out.DatabaseSecret
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	if in.MasterKeySecret != nil {
+		in, out := &in.MasterKeySecret, &out.MasterKeySecret
+		*out = new(v1.LocalObjectReference)
+		**out = **in
+	}
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]v1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuManagedSTS.
+func (in *RokkuManagedSTS) DeepCopy() *RokkuManagedSTS {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuManagedSTS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuMonitoring) DeepCopyInto(out *RokkuMonitoring) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Alerts != nil {
+		in, out := &in.Alerts, &out.Alerts
+		*out = new(RokkuAlerts)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuMonitoring.
+func (in *RokkuMonitoring) DeepCopy() *RokkuMonitoring {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuMonitoring)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuNetworkPolicy) DeepCopyInto(out *RokkuNetworkPolicy) {
+	*out = *in
+	if in.AllowedClients != nil {
+		in, out := &in.AllowedClients, &out.AllowedClients
+		*out = make([]networkingv1.NetworkPolicyPeer, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MetricsClients != nil {
+		in, out := &in.MetricsClients, &out.MetricsClients
+		*out = make([]networkingv1.NetworkPolicyPeer, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ExtraEgress != nil {
+		in, out := &in.ExtraEgress, &out.ExtraEgress
+		*out = make([]networkingv1.NetworkPolicyEgressRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuNetworkPolicy.
+func (in *RokkuNetworkPolicy) DeepCopy() *RokkuNetworkPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuNetworkPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuOIDC) DeepCopyInto(out *RokkuOIDC) {
+	*out = *in
+	if in.ClientSecret != nil {
+		in, out := &in.ClientSecret, &out.ClientSecret
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VerifyToken != nil {
+		in, out := &in.VerifyToken, &out.VerifyToken
+		*out = new(RokkuOIDCVerifyToken)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuOIDC.
+func (in *RokkuOIDC) DeepCopy() *RokkuOIDC {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuOIDC)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuOIDCVerifyToken) DeepCopyInto(out *RokkuOIDCVerifyToken) {
+	*out = *in
+	if in.CheckRealmURL != nil {
+		in, out := &in.CheckRealmURL, &out.CheckRealmURL
+		*out = new(bool)
+		**out = **in
+	}
+	if in.IssuerForList != nil {
+		in, out := &in.IssuerForList, &out.IssuerForList
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuOIDCVerifyToken.
+func (in *RokkuOIDCVerifyToken) DeepCopy() *RokkuOIDCVerifyToken {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuOIDCVerifyToken)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuOverrides) DeepCopyInto(out *RokkuOverrides) {
+	*out = *in
+	if in.Deployment != nil {
+		in, out := &in.Deployment, &out.Deployment
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Service != nil {
+		in, out := &in.Service, &out.Service
+		*out = new(runtime.RawExtension)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuOverrides.
+func (in *RokkuOverrides) DeepCopy() *RokkuOverrides {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuOverrides)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuPodTemplateSpec) DeepCopyInto(out *RokkuPodTemplateSpec) {
+	*out = *in
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(v1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]v1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]v1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.HighAvailability != nil {
+		in, out := &in.HighAvailability, &out.HighAvailability
+		*out = new(RokkuHighAvailability)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]v1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.DNSConfig != nil {
+		in, out := &in.DNSConfig, &out.DNSConfig
+		*out = new(v1.PodDNSConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.HostAliases != nil {
+		in, out := &in.HostAliases, &out.HostAliases
+		*out = make([]v1.HostAlias, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.RuntimeClassName != nil {
+		in, out := &in.RuntimeClassName, &out.RuntimeClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]v1.ContainerPort, len(*in))
+		copy(*out, *in)
+	}
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = new(int64)
+		**out = **in
+	}
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(v1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]v1.Volume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VolumeMounts != nil {
+		in, out := &in.VolumeMounts, &out.VolumeMounts
+		*out = make([]v1.VolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuPodTemplateSpec.
+func (in *RokkuPodTemplateSpec) DeepCopy() *RokkuPodTemplateSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuPodTemplateSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuProbe) DeepCopyInto(out *RokkuProbe) {
 	*out = *in
+	in.Probe.DeepCopyInto(&out.Probe)
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuEnvironment.
-func (in *RokkuEnvironment) DeepCopy() *RokkuEnvironment {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuProbe.
+func (in *RokkuProbe) DeepCopy() *RokkuProbe {
 	if in == nil {
 		return nil
 	}
-	out := new(RokkuEnvironment)
+	out := new(RokkuProbe)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RokkuLifecycle) DeepCopyInto(out *RokkuLifecycle) {
+func (in *RokkuProbes) DeepCopyInto(out *RokkuProbes) {
 	*out = *in
-	if in.PostStart != nil {
-		in, out := &in.PostStart, &out.PostStart
-		*out = new(RokkuLifecycleHandler)
+	if in.Liveness != nil {
+		in, out := &in.Liveness, &out.Liveness
+		*out = new(RokkuProbe)
 		(*in).DeepCopyInto(*out)
 	}
-	if in.PreStop != nil {
-		in, out := &in.PreStop, &out.PreStop
-		*out = new(RokkuLifecycleHandler)
+	if in.Readiness != nil {
+		in, out := &in.Readiness, &out.Readiness
+		*out = new(RokkuProbe)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Startup != nil {
+		in, out := &in.Startup, &out.Startup
+		*out = new(RokkuProbe)
 		(*in).DeepCopyInto(*out)
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuLifecycle.
-func (in *RokkuLifecycle) DeepCopy() *RokkuLifecycle {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuProbes.
+func (in *RokkuProbes) DeepCopy() *RokkuProbes {
 	if in == nil {
 		return nil
 	}
-	out := new(RokkuLifecycle)
+	out := new(RokkuProbes)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RokkuLifecycleHandler) DeepCopyInto(out *RokkuLifecycleHandler) {
+func (in *RokkuProxy) DeepCopyInto(out *RokkuProxy) {
 	*out = *in
-	if in.Exec != nil {
-		in, out := &in.Exec, &out.Exec
-		*out = new(v1.ExecAction)
-		(*in).DeepCopyInto(*out)
+	if in.NoProxy != nil {
+		in, out := &in.NoProxy, &out.NoProxy
+		*out = make([]string, len(*in))
+		copy(*out, *in)
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuLifecycleHandler.
-func (in *RokkuLifecycleHandler) DeepCopy() *RokkuLifecycleHandler {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuProxy.
+func (in *RokkuProxy) DeepCopy() *RokkuProxy {
 	if in == nil {
 		return nil
 	}
-	out := new(RokkuLifecycleHandler)
+	out := new(RokkuProxy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RokkuList) DeepCopyInto(out *RokkuList) {
+func (in *RokkuRoleRef) DeepCopyInto(out *RokkuRoleRef) {
 	*out = *in
-	out.TypeMeta = in.TypeMeta
-	in.ListMeta.DeepCopyInto(&out.ListMeta)
-	if in.Items != nil {
-		in, out := &in.Items, &out.Items
-		*out = make([]Rokku, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
-	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuList.
-func (in *RokkuList) DeepCopy() *RokkuList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuRoleRef.
+func (in *RokkuRoleRef) DeepCopy() *RokkuRoleRef {
 	if in == nil {
 		return nil
 	}
-	out := new(RokkuList)
+	out := new(RokkuRoleRef)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *RokkuList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
-	}
-	return nil
-}
-
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *RokkuPodTemplateSpec) DeepCopyInto(out *RokkuPodTemplateSpec) {
+func (in *RokkuRollout) DeepCopyInto(out *RokkuRollout) {
 	*out = *in
-	if in.Affinity != nil {
-		in, out := &in.Affinity, &out.Affinity
-		*out = new(v1.Affinity)
+	if in.Canary != nil {
+		in, out := &in.Canary, &out.Canary
+		*out = new(RokkuCanary)
 		(*in).DeepCopyInto(*out)
 	}
-	if in.Annotations != nil {
-		in, out := &in.Annotations, &out.Annotations
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
-	if in.Labels != nil {
-		in, out := &in.Labels, &out.Labels
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
-	}
-	if in.Ports != nil {
-		in, out := &in.Ports, &out.Ports
-		*out = make([]v1.ContainerPort, len(*in))
-		copy(*out, *in)
+	if in.BlueGreen != nil {
+		in, out := &in.BlueGreen, &out.BlueGreen
+		*out = new(RokkuBlueGreen)
+		(*in).DeepCopyInto(*out)
 	}
-	if in.TerminationGracePeriodSeconds != nil {
-		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
-		*out = new(int64)
+	if in.ProgressDeadlineSeconds != nil {
+		in, out := &in.ProgressDeadlineSeconds, &out.ProgressDeadlineSeconds
+		*out = new(int32)
 		**out = **in
 	}
-	if in.SecurityContext != nil {
-		in, out := &in.SecurityContext, &out.SecurityContext
-		*out = new(v1.SecurityContext)
-		(*in).DeepCopyInto(*out)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuRollout.
+func (in *RokkuRollout) DeepCopy() *RokkuRollout {
+	if in == nil {
+		return nil
 	}
-	if in.Volumes != nil {
-		in, out := &in.Volumes, &out.Volumes
-		*out = make([]v1.Volume, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	out := new(RokkuRollout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuSTS) DeepCopyInto(out *RokkuSTS) {
+	*out = *in
+	if in.Managed != nil {
+		in, out := &in.Managed, &out.Managed
+		*out = new(RokkuManagedSTS)
+		(*in).DeepCopyInto(*out)
 	}
-	if in.VolumeMounts != nil {
-		in, out := &in.VolumeMounts, &out.VolumeMounts
-		*out = make([]v1.VolumeMount, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+	if in.OIDC != nil {
+		in, out := &in.OIDC, &out.OIDC
+		*out = new(RokkuOIDC)
+		(*in).DeepCopyInto(*out)
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuPodTemplateSpec.
-func (in *RokkuPodTemplateSpec) DeepCopy() *RokkuPodTemplateSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuSTS.
+func (in *RokkuSTS) DeepCopy() *RokkuSTS {
 	if in == nil {
 		return nil
 	}
-	out := new(RokkuPodTemplateSpec)
+	out := new(RokkuSTS)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -308,6 +990,46 @@ func (in *RokkuService) DeepCopy() *RokkuService {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuServiceAccount) DeepCopyInto(out *RokkuServiceAccount) {
+	*out = *in
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.RoleRef != nil {
+		in, out := &in.RoleRef, &out.RoleRef
+		*out = new(RokkuRoleRef)
+		**out = **in
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]rbacv1.PolicyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AutomountServiceAccountToken != nil {
+		in, out := &in.AutomountServiceAccountToken, &out.AutomountServiceAccountToken
+		*out = new(bool)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuServiceAccount.
+func (in *RokkuServiceAccount) DeepCopy() *RokkuServiceAccount {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuServiceAccount)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RokkuSpec) DeepCopyInto(out *RokkuSpec) {
 	*out = *in
@@ -343,6 +1065,100 @@ func (in *RokkuSpec) DeepCopyInto(out *RokkuSpec) {
 		*out = new(RokkuEnvironment)
 		**out = **in
 	}
+	if in.Backends != nil {
+		in, out := &in.Backends, &out.Backends
+		*out = new(RokkuBackends)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NetworkPolicy != nil {
+		in, out := &in.NetworkPolicy, &out.NetworkPolicy
+		*out = new(RokkuNetworkPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Monitoring != nil {
+		in, out := &in.Monitoring, &out.Monitoring
+		*out = new(RokkuMonitoring)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Probes != nil {
+		in, out := &in.Probes, &out.Probes
+		*out = new(RokkuProbes)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RokkuRollout)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RevisionHistoryLimit != nil {
+		in, out := &in.RevisionHistoryLimit, &out.RevisionHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RollbackTo != nil {
+		in, out := &in.RollbackTo, &out.RollbackTo
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Maintenance != nil {
+		in, out := &in.Maintenance, &out.Maintenance
+		*out = new(RokkuMaintenance)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.InitContainers != nil {
+		in, out := &in.InitContainers, &out.InitContainers
+		*out = make([]v1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Sidecars != nil {
+		in, out := &in.Sidecars, &out.Sidecars
+		*out = make([]v1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Command != nil {
+		in, out := &in.Command, &out.Command
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.JVM != nil {
+		in, out := &in.JVM, &out.JVM
+		*out = new(RokkuJVM)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ServiceAccount != nil {
+		in, out := &in.ServiceAccount, &out.ServiceAccount
+		*out = new(RokkuServiceAccount)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Trust != nil {
+		in, out := &in.Trust, &out.Trust
+		*out = new(RokkuTrust)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Proxy != nil {
+		in, out := &in.Proxy, &out.Proxy
+		*out = new(RokkuProxy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.STS != nil {
+		in, out := &in.STS, &out.STS
+		*out = new(RokkuSTS)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Overrides != nil {
+		in, out := &in.Overrides, &out.Overrides
+		*out = new(RokkuOverrides)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -362,13 +1178,44 @@ func (in *RokkuStatus) DeepCopyInto(out *RokkuStatus) {
 	if in.Pods != nil {
 		in, out := &in.Pods, &out.Pods
 		*out = make([]PodStatus, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PodsSummary != nil {
+		in, out := &in.PodsSummary, &out.PodsSummary
+		*out = new(PodsSummary)
+		(*in).DeepCopyInto(*out)
 	}
 	if in.Services != nil {
 		in, out := &in.Services, &out.Services
 		*out = make([]ServiceStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Workload != nil {
+		in, out := &in.Workload, &out.Workload
+		*out = new(WorkloadStatus)
+		**out = **in
+	}
+	if in.SecurityViolations != nil {
+		in, out := &in.SecurityViolations, &out.SecurityViolations
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]RokkuCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -382,9 +1229,87 @@ func (in *RokkuStatus) DeepCopy() *RokkuStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RokkuTrust) DeepCopyInto(out *RokkuTrust) {
+	*out = *in
+	if in.CABundles != nil {
+		in, out := &in.CABundles, &out.CABundles
+		*out = make([]RokkuCABundle, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RokkuTrust.
+func (in *RokkuTrust) DeepCopy() *RokkuTrust {
+	if in == nil {
+		return nil
+	}
+	out := new(RokkuTrust)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStatus) DeepCopyInto(out *RolloutStatus) {
+	*out = *in
+	if in.StepStartTime != nil {
+		in, out := &in.StepStartTime, &out.StepStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStatus.
+func (in *RolloutStatus) DeepCopy() *RolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServicePortStatus) DeepCopyInto(out *ServicePortStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServicePortStatus.
+func (in *ServicePortStatus) DeepCopy() *ServicePortStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ServicePortStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ServiceStatus) DeepCopyInto(out *ServiceStatus) {
 	*out = *in
+	if in.Ports != nil {
+		in, out := &in.Ports, &out.Ports
+		*out = make([]ServicePortStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.LoadBalancerIngress != nil {
+		in, out := &in.LoadBalancerIngress, &out.LoadBalancerIngress
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Hosts != nil {
+		in, out := &in.Hosts, &out.Hosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -397,3 +1322,19 @@ func (in *ServiceStatus) DeepCopy() *ServiceStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadStatus) DeepCopyInto(out *WorkloadStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadStatus.
+func (in *WorkloadStatus) DeepCopy() *WorkloadStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadStatus)
+	in.DeepCopyInto(out)
+	return out
+}
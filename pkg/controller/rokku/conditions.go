@@ -0,0 +1,62 @@
+package rokku
+
+import (
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// setCondition adds or updates the condition of the given type. The
+// transition time is only bumped when the condition status changes.
+func setCondition(status *rokkuv1alpha1.RokkuStatus, condType rokkuv1alpha1.RokkuConditionType, condStatus corev1.ConditionStatus, reason, message string) {
+	for i := range status.Conditions {
+		cond := &status.Conditions[i]
+		if cond.Type != condType {
+			continue
+		}
+		if cond.Status != condStatus {
+			cond.LastTransitionTime = metav1.Now()
+		}
+		cond.Status = condStatus
+		cond.Reason = reason
+		cond.Message = message
+		return
+	}
+	status.Conditions = append(status.Conditions, rokkuv1alpha1.RokkuCondition{
+		Type:               condType,
+		Status:             condStatus,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	})
+}
+
+// isConditionTrue tells whether the condition of the given type is true.
+func isConditionTrue(status rokkuv1alpha1.RokkuStatus, condType rokkuv1alpha1.RokkuConditionType) bool {
+	for _, cond := range status.Conditions {
+		if cond.Type == condType {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// getCondition returns the condition of the given type, if any.
+func getCondition(status rokkuv1alpha1.RokkuStatus, condType rokkuv1alpha1.RokkuConditionType) *rokkuv1alpha1.RokkuCondition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == condType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// removeCondition removes the condition of the given type, if any.
+func removeCondition(status *rokkuv1alpha1.RokkuStatus, condType rokkuv1alpha1.RokkuConditionType) {
+	for i, cond := range status.Conditions {
+		if cond.Type == condType {
+			status.Conditions = append(status.Conditions[:i], status.Conditions[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package rokku
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"github.com/jwi078/rokku-operator/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// refreshConfigChecksum records the checksum of the content of the config
+// ConfigMap into the Rokku spec, so that the pods are replaced whenever the
+// ConfigMap changes. A missing ConfigMap is only reported, its checksum is
+// recorded once it's created.
+func (r *ReconcileRokku) refreshConfigChecksum(ctx context.Context, rokku *rokkuv1alpha1.Rokku) error {
+	conf := rokku.Spec.Config
+	if conf == nil || conf.Kind != rokkuv1alpha1.ConfigKindConfigMap {
+		removeCondition(&rokku.Status, rokkuv1alpha1.RokkuConfigAvailable)
+		return nil
+	}
+
+	var cm corev1.ConfigMap
+	err := r.client.Get(ctx, types.NamespacedName{Name: conf.Name, Namespace: rokku.Namespace}, &cm)
+	if errors.IsNotFound(err) {
+		setCondition(&rokku.Status, rokkuv1alpha1.RokkuConfigAvailable, corev1.ConditionFalse, "ConfigMapNotFound",
+			fmt.Sprintf("config ConfigMap %q not found", conf.Name))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to retrieve config ConfigMap %q: %v", conf.Name, err)
+	}
+	setCondition(&rokku.Status, rokkuv1alpha1.RokkuConfigAvailable, corev1.ConditionTrue, "ConfigMapFound", "")
+
+	keys := make([]string, 0, len(cm.Data)+len(cm.BinaryData))
+	for k := range cm.Data {
+		keys = append(keys, k)
+	}
+	for k := range cm.BinaryData {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(hash, "%s\x00%s\x00", k, cm.Data[k])
+		hash.Write(cm.BinaryData[k])
+	}
+
+	k8s.SetConfigChecksum(rokku, fmt.Sprintf("%x", hash.Sum(nil)))
+	return nil
+}
@@ -0,0 +1,81 @@
+package rokku
+
+import (
+	"context"
+	"testing"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"github.com/jwi078/rokku-operator/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRefreshConfigChecksum(t *testing.T) {
+	newConfigMap := func(data map[string]string, binaryData map[string][]byte) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "rokku-config", Namespace: "default"},
+			Data:       data,
+			BinaryData: binaryData,
+		}
+	}
+	checksum := func(t *testing.T, conf *rokkuv1alpha1.ConfigRef, cm *corev1.ConfigMap) (string, *rokkuv1alpha1.RokkuCondition) {
+		var r *ReconcileRokku
+		if cm != nil {
+			r = newTestReconciler(cm)
+		} else {
+			r = newTestReconciler()
+		}
+		rokku := newTestRokku()
+		rokku.Spec.Config = conf
+		if err := r.refreshConfigChecksum(context.Background(), rokku); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		obj := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: rokku.Spec.PodTemplate.Annotations}}
+		return k8s.GetConfigChecksumFromObject(obj), getCondition(rokku.Status, rokkuv1alpha1.RokkuConfigAvailable)
+	}
+	configMapRef := &rokkuv1alpha1.ConfigRef{Kind: rokkuv1alpha1.ConfigKindConfigMap, Name: "rokku-config"}
+
+	t.Run("no ConfigMap config", func(t *testing.T) {
+		for _, conf := range []*rokkuv1alpha1.ConfigRef{nil, {Kind: rokkuv1alpha1.ConfigKindInline, Name: "config", Value: "<xml/>"}} {
+			if sum, cond := checksum(t, conf, nil); sum != "" || cond != nil {
+				t.Errorf("checksum = %q, condition = %v, want neither", sum, cond)
+			}
+		}
+	})
+
+	t.Run("missing ConfigMap", func(t *testing.T) {
+		sum, cond := checksum(t, configMapRef, nil)
+		if sum != "" {
+			t.Errorf("checksum = %q, want none", sum)
+		}
+		if cond == nil || cond.Status != corev1.ConditionFalse || cond.Reason != "ConfigMapNotFound" {
+			t.Errorf("condition = %+v, want ConfigMapNotFound", cond)
+		}
+	})
+
+	t.Run("content changes", func(t *testing.T) {
+		base, cond := checksum(t, configMapRef, newConfigMap(map[string]string{"a": "1", "b": "2"}, nil))
+		if base == "" {
+			t.Fatal("expected a checksum")
+		}
+		if cond == nil || cond.Status != corev1.ConditionTrue {
+			t.Errorf("condition = %+v, want true", cond)
+		}
+
+		tests := []struct {
+			name string
+			cm   *corev1.ConfigMap
+			same bool
+		}{
+			{name: "same data", cm: newConfigMap(map[string]string{"b": "2", "a": "1"}, nil), same: true},
+			{name: "changed value", cm: newConfigMap(map[string]string{"a": "1", "b": "3"}, nil)},
+			{name: "value moved to another key", cm: newConfigMap(map[string]string{"a": "12", "b": ""}, nil)},
+			{name: "binary data", cm: newConfigMap(map[string]string{"a": "1", "b": "2"}, map[string][]byte{"c": {0}})},
+		}
+		for _, tt := range tests {
+			if sum, _ := checksum(t, configMapRef, tt.cm); (sum == base) != tt.same {
+				t.Errorf("%s: checksum equality = %v, want %v", tt.name, sum == base, tt.same)
+			}
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package rokku
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"github.com/jwi078/rokku-operator/pkg/k8s"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// reconcileDaemonSet runs Rokku through a DaemonSet. The Deployments left by
+// the Deployment workload kind are only removed once the DaemonSet is ready,
+// so that migrating between kinds doesn't drop the Rokku pods, unless both
+// run host network pods.
+func (r *ReconcileRokku) reconcileDaemonSet(ctx context.Context, rokku *rokkuv1alpha1.Rokku) (reconcile.Result, error) {
+	names := []string{rokku.Name, k8s.CanaryName(rokku.Name), k8s.ColorName(rokku.Name, k8s.ColorBlue), k8s.ColorName(rokku.Name, k8s.ColorGreen)}
+	if rokku.Spec.PodTemplate.HostNetwork {
+		for _, name := range names {
+			if err := r.deleteHostNetworkWorkload(ctx, rokku, name, &appv1.Deployment{}); err != nil {
+				return reconcile.Result{}, err
+			}
+		}
+	}
+
+	newDS, err := k8s.NewDaemonSet(rokku)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to assemble daemonset from Rokku: %v", err)
+	}
+
+	currDS := &appv1.DaemonSet{}
+	err = r.client.Get(ctx, types.NamespacedName{Name: newDS.Name, Namespace: newDS.Namespace}, currDS)
+	if err != nil && errors.IsNotFound(err) {
+		if err := r.client.Create(ctx, newDS); err != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to create daemonset: %v", err)
+		}
+		return reconcile.Result{}, nil
+	}
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to retrieve daemonset: %v", err)
+	}
+
+	rokku.Status.Workload = &rokkuv1alpha1.WorkloadStatus{
+		Kind:              rokkuv1alpha1.WorkloadKindDaemonSet,
+		Replicas:          currDS.Status.DesiredNumberScheduled,
+		ReadyReplicas:     currDS.Status.NumberReady,
+		UpdatedReplicas:   currDS.Status.UpdatedNumberScheduled,
+		AvailableReplicas: currDS.Status.NumberAvailable,
+	}
+
+	currSpec, err := k8s.ExtractRokkuSpec(currDS.ObjectMeta)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to extract rokku from daemonset: %v", err)
+	}
+
+	if !reflect.DeepEqual(rokku.Spec, currSpec) {
+		currDS.Spec = newDS.Spec
+		if err := k8s.SetRokkuSpec(&currDS.ObjectMeta, rokku.Spec); err != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to set rokku spec into object meta: %v", err)
+		}
+		if err := r.client.Update(ctx, currDS); err != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to update daemonset: %v", err)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	if !daemonSetReady(currDS) {
+		setCondition(&rokku.Status, rokkuv1alpha1.RokkuProgressing, corev1.ConditionTrue, "DaemonSetProgressing",
+			fmt.Sprintf("%d of %d DaemonSet pods are ready", currDS.Status.NumberReady, currDS.Status.DesiredNumberScheduled))
+		return reconcile.Result{}, nil
+	}
+	markRevisionComplete(rokku)
+	setCondition(&rokku.Status, rokkuv1alpha1.RokkuProgressing, corev1.ConditionTrue, "DaemonSetReady", "")
+
+	for _, name := range names {
+		if err := r.deleteIfExists(ctx, types.NamespacedName{Name: name, Namespace: rokku.Namespace}, &appv1.Deployment{}); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	rokku.Status.ActiveColor = ""
+	rokku.Status.Rollout = nil
+
+	return reconcile.Result{}, nil
+}
+
+// deleteDaemonSet removes the DaemonSet left by the DaemonSet workload kind
+// once the given Deployment replacing it is ready.
+func (r *ReconcileRokku) deleteDaemonSet(ctx context.Context, rokku *rokkuv1alpha1.Rokku, deploy *appv1.Deployment) error {
+	if !deploymentReady(deploy) {
+		return nil
+	}
+	return r.deleteIfExists(ctx, types.NamespacedName{Name: rokku.Name, Namespace: rokku.Namespace}, &appv1.DaemonSet{})
+}
+
+// deleteHostNetworkWorkload removes the workload left by the other workload
+// kind when both run host network pods. Their pods bind the same host ports,
+// so the pods replacing them can't be scheduled on the same nodes until
+// they're gone, and waiting for them to be ready would never end.
+func (r *ReconcileRokku) deleteHostNetworkWorkload(ctx context.Context, rokku *rokkuv1alpha1.Rokku, name string, obj runtime.Object) error {
+	nsName := types.NamespacedName{Name: name, Namespace: rokku.Namespace}
+	err := r.client.Get(ctx, nsName, obj)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to retrieve workload %q: %v", name, err)
+	}
+
+	var hostNetwork bool
+	switch w := obj.(type) {
+	case *appv1.Deployment:
+		hostNetwork = w.Spec.Template.Spec.HostNetwork
+	case *appv1.DaemonSet:
+		hostNetwork = w.Spec.Template.Spec.HostNetwork
+	}
+	if !hostNetwork {
+		return nil
+	}
+
+	if err := r.client.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete workload %q: %v", name, err)
+	}
+	r.recorder.Eventf(rokku, corev1.EventTypeNormal, "HostNetworkWorkloadDeleted",
+		"deleted %s before its replacement is ready, as host network pods of both can't run on the same nodes", name)
+	return nil
+}
+
+func daemonSetReady(ds *appv1.DaemonSet) bool {
+	return ds.Generation > 0 &&
+		ds.Status.ObservedGeneration >= ds.Generation &&
+		ds.Status.UpdatedNumberScheduled >= ds.Status.DesiredNumberScheduled &&
+		ds.Status.NumberReady >= ds.Status.DesiredNumberScheduled
+}
+
+func deploymentWorkloadStatus(deploy *appv1.Deployment) *rokkuv1alpha1.WorkloadStatus {
+	var replicas int32 = 1
+	if deploy.Spec.Replicas != nil {
+		replicas = *deploy.Spec.Replicas
+	}
+	return &rokkuv1alpha1.WorkloadStatus{
+		Kind:              rokkuv1alpha1.WorkloadKindDeployment,
+		Replicas:          replicas,
+		ReadyReplicas:     deploy.Status.ReadyReplicas,
+		UpdatedReplicas:   deploy.Status.UpdatedReplicas,
+		AvailableReplicas: deploy.Status.AvailableReplicas,
+	}
+}
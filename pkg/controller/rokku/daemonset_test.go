@@ -0,0 +1,118 @@
+package rokku
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"github.com/jwi078/rokku-operator/pkg/k8s"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// setDaemonSetReady marks every pod of the Rokku DaemonSet as ready.
+func setDaemonSetReady(t *testing.T, r *ReconcileRokku) {
+	ds := &appv1.DaemonSet{}
+	if err := r.client.Get(context.Background(), types.NamespacedName{Name: "rokku", Namespace: "default"}, ds); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ds.Generation = 1
+	ds.Status.ObservedGeneration = 1
+	ds.Status.DesiredNumberScheduled = 3
+	ds.Status.UpdatedNumberScheduled = 3
+	ds.Status.NumberReady = 3
+	if err := r.client.Update(context.Background(), ds); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func exists(t *testing.T, r *ReconcileRokku, obj runtime.Object) bool {
+	err := r.client.Get(context.Background(), types.NamespacedName{Name: "rokku", Namespace: "default"}, obj)
+	if errors.IsNotFound(err) {
+		return false
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return true
+}
+
+func TestReconcileDaemonSetMigration(t *testing.T) {
+	ctx := context.Background()
+	for _, hostNetwork := range []bool{false, true} {
+		previous := newTestRokku()
+		previous.Spec.PodTemplate.HostNetwork = hostNetwork
+		deploy, err := k8s.NewDeployment(previous)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		r := newTestReconciler(deploy)
+
+		rokku := newTestRokku()
+		rokku.Spec.WorkloadKind = rokkuv1alpha1.WorkloadKindDaemonSet
+		rokku.Spec.PodTemplate.HostNetwork = hostNetwork
+		for i := 0; i < 2; i++ {
+			if _, err := r.reconcileDaemonSet(ctx, rokku); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if !exists(t, r, &appv1.DaemonSet{}) {
+			t.Fatalf("hostNetwork %v: expected the DaemonSet", hostNetwork)
+		}
+
+		// Host network pods can't be scheduled next to the old ones, which
+		// are removed right away then
+		if got := exists(t, r, &appv1.Deployment{}); got == hostNetwork {
+			t.Errorf("hostNetwork %v: Deployment exists = %v before the DaemonSet is ready", hostNetwork, got)
+		}
+		if event := nextEvent(r); hostNetwork != strings.Contains(event, "HostNetworkWorkloadDeleted") {
+			t.Errorf("hostNetwork %v: unexpected event %q", hostNetwork, event)
+		}
+		cond := getCondition(rokku.Status, rokkuv1alpha1.RokkuProgressing)
+		if cond == nil || cond.Reason != "DaemonSetProgressing" {
+			t.Errorf("hostNetwork %v: progressing condition = %+v, want DaemonSetProgressing", hostNetwork, cond)
+		}
+
+		setDaemonSetReady(t, r)
+		if _, err := r.reconcileDaemonSet(ctx, rokku); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if exists(t, r, &appv1.Deployment{}) {
+			t.Errorf("hostNetwork %v: the Deployment must be removed once the DaemonSet is ready", hostNetwork)
+		}
+		if cond := getCondition(rokku.Status, rokkuv1alpha1.RokkuProgressing); cond == nil || cond.Reason != "DaemonSetReady" {
+			t.Errorf("hostNetwork %v: progressing condition = %+v, want DaemonSetReady", hostNetwork, cond)
+		}
+	}
+}
+
+func TestReconcileDeploymentRemovesHostNetworkDaemonSet(t *testing.T) {
+	ctx := context.Background()
+	previous := newTestRokku()
+	previous.Spec.WorkloadKind = rokkuv1alpha1.WorkloadKindDaemonSet
+	previous.Spec.PodTemplate.HostNetwork = true
+	ds, err := k8s.NewDaemonSet(previous)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := newTestReconciler(ds)
+
+	rokku := newTestRokku()
+	rokku.Spec.PodTemplate.HostNetwork = true
+	if _, err := r.reconcileDeployment(ctx, rokku); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if exists(t, r, &appv1.DaemonSet{}) {
+		t.Errorf("the host network DaemonSet must be removed before the Deployment is ready")
+	}
+	if !exists(t, r, &appv1.Deployment{}) {
+		t.Errorf("expected the Deployment")
+	}
+	if event := nextEvent(r); !strings.Contains(event, corev1.EventTypeNormal+" HostNetworkWorkloadDeleted") {
+		t.Errorf("event = %q, want HostNetworkWorkloadDeleted", event)
+	}
+}
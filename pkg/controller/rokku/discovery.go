@@ -0,0 +1,35 @@
+package rokku
+
+import (
+	"time"
+
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// discoveryTTL bounds how long the discovered APIs are cached, so that APIs
+// installed after the operator started, such as the prometheus-operator
+// ones, are eventually picked up.
+const discoveryTTL = 10 * time.Minute
+
+// resourceExists tells whether the cluster serves the given kind, using the
+// cached discovery results.
+func (r *ReconcileRokku) resourceExists(groupVersion, kind string) (bool, error) {
+	r.discoveryMu.Lock()
+	if time.Since(r.discoveryRefreshed) > discoveryTTL {
+		r.discovery.Invalidate()
+		r.discoveryRefreshed = time.Now()
+	}
+	r.discoveryMu.Unlock()
+
+	return k8sutil.ResourceExists(r.discovery, groupVersion, kind)
+}
+
+// invalidateDiscoveryOnNoMatch drops the cached discovery results when err
+// tells that a kind is no longer served, e.g. after its CRD was removed.
+func (r *ReconcileRokku) invalidateDiscoveryOnNoMatch(err error) error {
+	if meta.IsNoMatchError(err) {
+		r.discovery.Invalidate()
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+package rokku
+
+import (
+	"context"
+	"fmt"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"github.com/jwi078/rokku-operator/pkg/k8s"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var routeGVK = schema.GroupVersionKind{Group: "route.openshift.io", Version: "v1", Kind: "RouteList"}
+
+type hostStatus struct {
+	host string
+	tls  bool
+}
+
+// listHosts returns the hosts of the Ingresses and OpenShift Routes pointing
+// to the Rokku services, indexed by service name.
+func (r *ReconcileRokku) listHosts(ctx context.Context, rokku *rokkuv1alpha1.Rokku) (map[string][]hostStatus, error) {
+	hosts := make(map[string][]hostStatus)
+	svcName := k8s.ServiceName(rokku.Name)
+
+	ingressList := &networkingv1beta1.IngressList{}
+	if err := r.client.List(ctx, ingressList, client.InNamespace(rokku.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list ingresses: %v", err)
+	}
+	for _, ing := range ingressList.Items {
+		tlsHosts := make(map[string]bool)
+		for _, tls := range ing.Spec.TLS {
+			for _, h := range tls.Hosts {
+				tlsHosts[h] = true
+			}
+		}
+		for _, rule := range ing.Spec.Rules {
+			if rule.Host == "" || rule.HTTP == nil {
+				continue
+			}
+			for _, path := range rule.HTTP.Paths {
+				if path.Backend.ServiceName == svcName {
+					hosts[svcName] = append(hosts[svcName], hostStatus{host: rule.Host, tls: tlsHosts[rule.Host]})
+					break
+				}
+			}
+		}
+	}
+
+	exists, err := r.resourceExists(routeGVK.GroupVersion().String(), "Route")
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover Route API: %v", err)
+	}
+	if !exists {
+		return hosts, nil
+	}
+
+	routeList := &unstructured.UnstructuredList{}
+	routeList.SetGroupVersionKind(routeGVK)
+	if err := r.client.List(ctx, routeList, client.InNamespace(rokku.Namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list routes: %v", r.invalidateDiscoveryOnNoMatch(err))
+	}
+	for _, route := range routeList.Items {
+		to, _, _ := unstructured.NestedString(route.Object, "spec", "to", "name")
+		host, _, _ := unstructured.NestedString(route.Object, "spec", "host")
+		if to != svcName || host == "" {
+			continue
+		}
+		_, tls, _ := unstructured.NestedMap(route.Object, "spec", "tls")
+		hosts[svcName] = append(hosts[svcName], hostStatus{host: host, tls: tls})
+	}
+
+	return hosts, nil
+}
+
+// rokkuEndpoint returns the URL clients should use to reach Rokku, preferring
+// Ingress and Route hosts over load balancers over the cluster DNS name.
+func rokkuEndpoint(rokku *rokkuv1alpha1.Rokku, services []rokkuv1alpha1.ServiceStatus, hosts map[string][]hostStatus) string {
+	svcName := k8s.ServiceName(rokku.Name)
+	for _, h := range hosts[svcName] {
+		if h.tls {
+			return "https://" + h.host
+		}
+	}
+	if h := hosts[svcName]; len(h) > 0 {
+		return "http://" + h[0].host
+	}
+	for _, svc := range services {
+		if svc.Name != svcName {
+			continue
+		}
+		if len(svc.LoadBalancerIngress) > 0 {
+			return "http://" + svc.LoadBalancerIngress[0]
+		}
+		return "http://" + svc.DNSName
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package rokku
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"github.com/jwi078/rokku-operator/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestRefreshStatusEndpoints(t *testing.T) {
+	rokku := newTestRokku()
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "rokku-service", Namespace: "default", Labels: k8s.LabelsForRokku("rokku")},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.96.0.10",
+			Ports:     []corev1.ServicePort{{Name: "http", Port: 80, NodePort: 30080}},
+		},
+		Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+			Ingress: []corev1.LoadBalancerIngress{{IP: "192.0.2.1"}, {Hostname: "lb.example.com"}},
+		}},
+	}
+	backend := networkingv1beta1.IngressBackend{ServiceName: "rokku-service"}
+	ingress := &networkingv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "rokku", Namespace: "default"},
+		Spec: networkingv1beta1.IngressSpec{
+			TLS: []networkingv1beta1.IngressTLS{{Hosts: []string{"s3.example.com"}}},
+			Rules: []networkingv1beta1.IngressRule{
+				{Host: "s3-plain.example.com", IngressRuleValue: networkingv1beta1.IngressRuleValue{HTTP: &networkingv1beta1.HTTPIngressRuleValue{
+					Paths: []networkingv1beta1.HTTPIngressPath{{Backend: backend}},
+				}}},
+				{Host: "s3.example.com", IngressRuleValue: networkingv1beta1.IngressRuleValue{HTTP: &networkingv1beta1.HTTPIngressRuleValue{
+					Paths: []networkingv1beta1.HTTPIngressPath{{Backend: backend}},
+				}}},
+				{Host: "other.example.com", IngressRuleValue: networkingv1beta1.IngressRuleValue{HTTP: &networkingv1beta1.HTTPIngressRuleValue{
+					Paths: []networkingv1beta1.HTTPIngressPath{{Backend: networkingv1beta1.IngressBackend{ServiceName: "other"}}},
+				}}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		objs         []runtime.Object
+		wantHosts    []string
+		wantEndpoint string
+	}{
+		{name: "ingress", objs: []runtime.Object{ingress}, wantHosts: []string{"s3-plain.example.com", "s3.example.com"}, wantEndpoint: "https://s3.example.com"},
+		{name: "load balancer", wantEndpoint: "http://192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := rokku.DeepCopy()
+			r := newTestReconciler(append(tt.objs, instance, svc.DeepCopy())...)
+
+			if err := r.refreshStatus(context.Background(), instance, instance.Status.DeepCopy()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(instance.Status.Services) != 1 {
+				t.Fatalf("got %d services, want 1", len(instance.Status.Services))
+			}
+			want := rokkuv1alpha1.ServiceStatus{
+				Name:                "rokku-service",
+				DNSName:             "rokku-service.default.svc.cluster.local",
+				ClusterIP:           "10.96.0.10",
+				Ports:               []rokkuv1alpha1.ServicePortStatus{{Name: "http", Port: 80, NodePort: 30080}},
+				LoadBalancerIngress: []string{"192.0.2.1", "lb.example.com"},
+				Hosts:               tt.wantHosts,
+			}
+			if got := instance.Status.Services[0]; !reflect.DeepEqual(got, want) {
+				t.Errorf("service status = %+v, want %+v", got, want)
+			}
+			if instance.Status.Endpoint != tt.wantEndpoint {
+				t.Errorf("endpoint = %q, want %q", instance.Status.Endpoint, tt.wantEndpoint)
+			}
+		})
+	}
+}
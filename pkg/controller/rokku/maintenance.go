@@ -0,0 +1,72 @@
+package rokku
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"github.com/jwi078/rokku-operator/pkg/k8s"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// reconcileMaintenance runs the maintenance responder while the maintenance
+// mode is enabled and removes it afterwards. Scaling Rokku down and switching
+// the Service is done by their own reconcilers.
+func (r *ReconcileRokku) reconcileMaintenance(ctx context.Context, rokku *rokkuv1alpha1.Rokku) error {
+	name := types.NamespacedName{
+		Name:      k8s.MaintenanceName(rokku.Name),
+		Namespace: rokku.Namespace,
+	}
+
+	if rokku.Spec.Maintenance == nil || !rokku.Spec.Maintenance.Enabled {
+		if err := r.deleteIfExists(ctx, name, &appv1.Deployment{}); err != nil {
+			return err
+		}
+		return r.deleteIfExists(ctx, name, &corev1.ConfigMap{})
+	}
+
+	newConfigMap := k8s.NewMaintenanceConfigMap(rokku)
+	var currConfigMap corev1.ConfigMap
+	err := r.client.Get(ctx, name, &currConfigMap)
+	switch {
+	case errors.IsNotFound(err):
+		if err := r.client.Create(ctx, newConfigMap); err != nil {
+			return fmt.Errorf("failed to create maintenance configmap: %v", err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to retrieve maintenance configmap: %v", err)
+	case !reflect.DeepEqual(currConfigMap.Data, newConfigMap.Data):
+		currConfigMap.Data = newConfigMap.Data
+		if err := r.client.Update(ctx, &currConfigMap); err != nil {
+			return fmt.Errorf("failed to update maintenance configmap: %v", err)
+		}
+	}
+
+	newDeploy, err := k8s.NewMaintenanceDeployment(rokku)
+	if err != nil {
+		return fmt.Errorf("failed to assemble maintenance deployment from Rokku: %v", err)
+	}
+	var currDeploy appv1.Deployment
+	err = r.client.Get(ctx, name, &currDeploy)
+	switch {
+	case errors.IsNotFound(err):
+		if err := r.client.Create(ctx, newDeploy); err != nil {
+			return fmt.Errorf("failed to create maintenance deployment: %v", err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to retrieve maintenance deployment: %v", err)
+	case k8s.GetConfigChecksumFromObject(&currDeploy) != k8s.GetConfigChecksumFromObject(newDeploy) ||
+		!reflect.DeepEqual(currDeploy.Spec.Replicas, newDeploy.Spec.Replicas):
+		currDeploy.Spec = newDeploy.Spec
+		currDeploy.Annotations = newDeploy.Annotations
+		if err := r.client.Update(ctx, &currDeploy); err != nil {
+			return fmt.Errorf("failed to update maintenance deployment: %v", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package rokku
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestReconcileMaintenance(t *testing.T) {
+	ctx := context.Background()
+	name := types.NamespacedName{Name: "rokku-maintenance", Namespace: "default"}
+	rokku := newTestRokku()
+	rokku.Spec.Maintenance = &rokkuv1alpha1.RokkuMaintenance{Enabled: true}
+	r := newTestReconciler()
+
+	responder := func() *appv1.Deployment {
+		deploy := &appv1.Deployment{}
+		if err := r.client.Get(ctx, name, deploy); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return deploy
+	}
+	reconcileMaintenance := func() {
+		if err := r.reconcileMaintenance(ctx, rokku); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	reconcileMaintenance()
+	if err := r.client.Get(ctx, name, &corev1.ConfigMap{}); err != nil {
+		t.Fatalf("expected the responder ConfigMap: %v", err)
+	}
+
+	// Scaling the responder by hand is reverted
+	deploy := responder()
+	three := int32(3)
+	deploy.Spec.Replicas = &three
+	if err := r.client.Update(ctx, deploy); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reconcileMaintenance()
+	if replicas := *responder().Spec.Replicas; replicas != 1 {
+		t.Errorf("responder replicas = %d, want 1", replicas)
+	}
+
+	// Scheduling changes inherited from the pod template are rolled out
+	rokku.Spec.PodTemplate.NodeSelector = map[string]string{"pool": "edge"}
+	reconcileMaintenance()
+	if got := responder().Spec.Template.Spec.NodeSelector; !reflect.DeepEqual(got, rokku.Spec.PodTemplate.NodeSelector) {
+		t.Errorf("responder node selector = %v, want %v", got, rokku.Spec.PodTemplate.NodeSelector)
+	}
+
+	rokku.Spec.Maintenance.Enabled = false
+	reconcileMaintenance()
+	if err := r.client.Get(ctx, name, &appv1.Deployment{}); !errors.IsNotFound(err) {
+		t.Errorf("responder Deployment must be removed, got %v", err)
+	}
+	if err := r.client.Get(ctx, name, &corev1.ConfigMap{}); !errors.IsNotFound(err) {
+		t.Errorf("responder ConfigMap must be removed, got %v", err)
+	}
+}
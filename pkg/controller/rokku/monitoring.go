@@ -0,0 +1,159 @@
+package rokku
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"github.com/jwi078/rokku-operator/pkg/k8s"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func (r *ReconcileRokku) reconcileMonitor(ctx context.Context, rokku *rokkuv1alpha1.Rokku) error {
+	name := types.NamespacedName{
+		Name:      k8s.MonitorName(rokku.Name),
+		Namespace: rokku.Namespace,
+	}
+
+	logger := log.WithName("reconcileMonitor").WithValues("Monitor", name)
+
+	if rokku.Spec.Monitoring == nil {
+		// No discovery is needed to clean up, deleteIfExists ignores the
+		// kinds the cluster doesn't serve
+		if err := r.deleteIfExists(ctx, name, &monitoringv1.ServiceMonitor{}); err != nil {
+			return err
+		}
+		return r.deleteIfExists(ctx, name, &monitoringv1.PodMonitor{})
+	}
+
+	kind := rokku.Spec.Monitoring.MonitorKind
+	if kind == "" {
+		kind = rokkuv1alpha1.MonitorKindServiceMonitor
+	}
+
+	for _, k := range []rokkuv1alpha1.MonitorKind{rokkuv1alpha1.MonitorKindServiceMonitor, rokkuv1alpha1.MonitorKindPodMonitor} {
+		exists, err := r.resourceExists(monitoringv1.SchemeGroupVersion.String(), string(k))
+		if err != nil {
+			return fmt.Errorf("failed to discover %s API: %v", k, err)
+		}
+		if !exists {
+			if k == kind {
+				logger.Info("Monitor API not available in the cluster, skipping", "Kind", k)
+			}
+			continue
+		}
+
+		var current, desired runtime.Object
+		switch k {
+		case rokkuv1alpha1.MonitorKindServiceMonitor:
+			current = &monitoringv1.ServiceMonitor{}
+			if k == kind {
+				desired = k8s.NewServiceMonitor(rokku)
+			}
+		case rokkuv1alpha1.MonitorKindPodMonitor:
+			current = &monitoringv1.PodMonitor{}
+			if k == kind {
+				desired = k8s.NewPodMonitor(rokku)
+			}
+		}
+
+		if desired == nil {
+			if err := r.deleteIfExists(ctx, name, current); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := r.createOrUpdateMonitoring(ctx, name, current, desired); err != nil {
+			return r.invalidateDiscoveryOnNoMatch(err)
+		}
+	}
+
+	return nil
+}
+
+func (r *ReconcileRokku) reconcilePrometheusRule(ctx context.Context, rokku *rokkuv1alpha1.Rokku) error {
+	name := types.NamespacedName{
+		Name:      k8s.PrometheusRuleName(rokku.Name),
+		Namespace: rokku.Namespace,
+	}
+
+	if rokku.Spec.Monitoring == nil || rokku.Spec.Monitoring.Alerts == nil {
+		return r.deleteIfExists(ctx, name, &monitoringv1.PrometheusRule{})
+	}
+
+	exists, err := r.resourceExists(monitoringv1.SchemeGroupVersion.String(), monitoringv1.PrometheusRuleKind)
+	if err != nil {
+		return fmt.Errorf("failed to discover %s API: %v", monitoringv1.PrometheusRuleKind, err)
+	}
+	if !exists {
+		log.WithName("reconcilePrometheusRule").WithValues("PrometheusRule", name).
+			Info("PrometheusRule API not available in the cluster, skipping")
+		return nil
+	}
+
+	rule, err := k8s.NewPrometheusRule(rokku)
+	if err != nil {
+		return err
+	}
+	err = r.createOrUpdateMonitoring(ctx, name, &monitoringv1.PrometheusRule{}, rule)
+	return r.invalidateDiscoveryOnNoMatch(err)
+}
+
+// createOrUpdateMonitoring creates desired or, when its spec or labels have
+// drifted, updates the existing object. current holds the object retrieved
+// from the cluster.
+func (r *ReconcileRokku) createOrUpdateMonitoring(ctx context.Context, name types.NamespacedName, current, desired runtime.Object) error {
+	err := r.client.Get(ctx, name, current)
+	if err != nil && errors.IsNotFound(err) {
+		return r.client.Create(ctx, desired)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to retrieve %s: %v", name, err)
+	}
+
+	switch c := current.(type) {
+	case *monitoringv1.ServiceMonitor:
+		d := desired.(*monitoringv1.ServiceMonitor)
+		if reflect.DeepEqual(c.Spec, d.Spec) && reflect.DeepEqual(c.Labels, d.Labels) {
+			return nil
+		}
+		d.ResourceVersion = c.ResourceVersion
+	case *monitoringv1.PodMonitor:
+		d := desired.(*monitoringv1.PodMonitor)
+		if reflect.DeepEqual(c.Spec, d.Spec) && reflect.DeepEqual(c.Labels, d.Labels) {
+			return nil
+		}
+		d.ResourceVersion = c.ResourceVersion
+	case *monitoringv1.PrometheusRule:
+		d := desired.(*monitoringv1.PrometheusRule)
+		if reflect.DeepEqual(c.Spec, d.Spec) && reflect.DeepEqual(c.Labels, d.Labels) {
+			return nil
+		}
+		d.ResourceVersion = c.ResourceVersion
+	}
+
+	return r.client.Update(ctx, desired)
+}
+
+// deleteIfExists removes the object with the given name, if any. obj is used
+// to hold the object retrieved from the cluster. Kinds the cluster doesn't
+// serve have nothing to delete.
+func (r *ReconcileRokku) deleteIfExists(ctx context.Context, name types.NamespacedName, obj runtime.Object) error {
+	err := r.client.Get(ctx, name, obj)
+	if errors.IsNotFound(err) || meta.IsNoMatchError(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to retrieve %s: %v", name, err)
+	}
+	if err := r.client.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete %s: %v", name, err)
+	}
+	return nil
+}
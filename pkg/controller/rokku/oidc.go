@@ -0,0 +1,147 @@
+package rokku
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"github.com/jwi078/rokku-operator/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	oidcTimeout = 10 * time.Second
+	// oidcCheckInterval is how long the outcome of a discovery check is
+	// reused before the issuer is checked again.
+	oidcCheckInterval = 5 * time.Minute
+)
+
+// oidcCheck is the outcome of a discovery check.
+type oidcCheck struct {
+	err       error
+	checkedAt time.Time
+}
+
+// reconcileOIDC checks the discovery document of the OIDC issuer and reports
+// the outcome in the OIDCReachable condition. An unreachable issuer doesn't
+// stop the reconciliation. It returns when the issuer is due to be checked
+// again.
+func (r *ReconcileRokku) reconcileOIDC(ctx context.Context, rokku *rokkuv1alpha1.Rokku) time.Duration {
+	if rokku.Spec.STS == nil || rokku.Spec.STS.OIDC == nil {
+		removeCondition(&rokku.Status, rokkuv1alpha1.RokkuOIDCReachable)
+		return 0
+	}
+
+	check := r.checkOIDC(ctx, rokku)
+	requeueAfter := oidcCheckInterval - time.Since(check.checkedAt)
+	if err := check.err; err != nil {
+		if isConditionTrue(rokku.Status, rokkuv1alpha1.RokkuOIDCReachable) {
+			r.recorder.Event(rokku, corev1.EventTypeWarning, "OIDCUnreachable", err.Error())
+		}
+		setCondition(&rokku.Status, rokkuv1alpha1.RokkuOIDCReachable, corev1.ConditionFalse, "DiscoveryFailed", err.Error())
+		return requeueAfter
+	}
+	setCondition(&rokku.Status, rokkuv1alpha1.RokkuOIDCReachable, corev1.ConditionTrue, "DiscoverySucceeded", "")
+	return requeueAfter
+}
+
+// checkOIDC returns the outcome of the last discovery check of the issuer, or
+// checks it again once it's older than oidcCheckInterval. The outcomes are
+// shared by the Rokkus using the same issuer, trust and proxy settings.
+func (r *ReconcileRokku) checkOIDC(ctx context.Context, rokku *rokkuv1alpha1.Rokku) oidcCheck {
+	settings, _ := json.Marshal([]interface{}{rokku.Namespace, rokku.Spec.Trust, rokku.Spec.Proxy})
+	key := rokku.Spec.STS.OIDC.IssuerURL + "\x00" + string(settings)
+
+	r.oidcMu.Lock()
+	check, found := r.oidcChecks[key]
+	r.oidcMu.Unlock()
+	if found && time.Since(check.checkedAt) < oidcCheckInterval {
+		return check
+	}
+
+	client, err := r.oidcClient(ctx, rokku)
+	if err == nil {
+		err = checkOIDCDiscovery(ctx, client, rokku.Spec.STS.OIDC)
+	}
+	check = oidcCheck{err: err, checkedAt: time.Now()}
+
+	r.oidcMu.Lock()
+	defer r.oidcMu.Unlock()
+	if r.oidcChecks == nil {
+		r.oidcChecks = make(map[string]oidcCheck)
+	}
+	for k, c := range r.oidcChecks {
+		if time.Since(c.checkedAt) >= oidcCheckInterval {
+			delete(r.oidcChecks, k)
+		}
+	}
+	r.oidcChecks[key] = check
+	return check
+}
+
+// oidcClient returns the HTTP client reaching the issuer the way Rokku does,
+// trusting the CA bundles on top of the system ones and going through the
+// Rokku proxy.
+func (r *ReconcileRokku) oidcClient(ctx context.Context, rokku *rokkuv1alpha1.Rokku) (*http.Client, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	// The missing CA bundles are reported by refreshTrustChecksum
+	bundles, _, err := r.caBundles(ctx, rokku)
+	if err != nil {
+		return nil, err
+	}
+	for _, data := range bundles {
+		pool.AppendCertsFromPEM(data)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	transport.DisableKeepAlives = true
+	if proxy := k8s.ProxyFunc(rokku.Spec); proxy != nil {
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxy(req.URL)
+		}
+	}
+	return &http.Client{Transport: transport, Timeout: oidcTimeout}, nil
+}
+
+// checkOIDCDiscovery fetches the discovery document of the issuer and checks
+// it describes the issuer.
+func checkOIDCDiscovery(ctx context.Context, client *http.Client, oidc *rokkuv1alpha1.RokkuOIDC) error {
+	req, err := http.NewRequest(http.MethodGet, k8s.OIDCDiscoveryURL(oidc), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from the OIDC discovery endpoint", resp.StatusCode)
+	}
+
+	var doc struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("failed to decode OIDC discovery document: %v", err)
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != strings.TrimSuffix(oidc.IssuerURL, "/") {
+		return fmt.Errorf("OIDC discovery document is for issuer %q", doc.Issuer)
+	}
+	if doc.JWKSURI == "" {
+		return fmt.Errorf("OIDC discovery document has no jwks_uri")
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package rokku
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testRealmPath = "/auth/realms/rokku"
+
+// newDiscoveryServer returns a fake OIDC issuer serving the document returned
+// by doc, given the issuer URL, with the given status.
+func newDiscoveryServer(status int, doc func(issuer string) map[string]string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != testRealmPath+"/.well-known/openid-configuration" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(doc(server.URL + testRealmPath))
+	}))
+	return server
+}
+
+func TestCheckOIDCDiscovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		doc     func(issuer string) map[string]string
+		wantErr string
+	}{
+		{
+			name:   "valid document",
+			status: http.StatusOK,
+			doc: func(issuer string) map[string]string {
+				return map[string]string{"issuer": issuer, "jwks_uri": issuer + "/protocol/openid-connect/certs"}
+			},
+		},
+		{
+			name:   "trailing slash in the issuer",
+			status: http.StatusOK,
+			doc: func(issuer string) map[string]string {
+				return map[string]string{"issuer": issuer + "/", "jwks_uri": issuer + "/protocol/openid-connect/certs"}
+			},
+		},
+		{
+			name:   "non-200 status",
+			status: http.StatusServiceUnavailable,
+			doc: func(issuer string) map[string]string {
+				return map[string]string{}
+			},
+			wantErr: "unexpected status 503",
+		},
+		{
+			name:   "issuer mismatch",
+			status: http.StatusOK,
+			doc: func(issuer string) map[string]string {
+				return map[string]string{"issuer": "https://other/auth/realms/rokku", "jwks_uri": issuer + "/certs"}
+			},
+			wantErr: `is for issuer "https://other/auth/realms/rokku"`,
+		},
+		{
+			name:   "missing jwks_uri",
+			status: http.StatusOK,
+			doc: func(issuer string) map[string]string {
+				return map[string]string{"issuer": issuer}
+			},
+			wantErr: "has no jwks_uri",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newDiscoveryServer(tt.status, tt.doc)
+			defer server.Close()
+
+			oidc := &rokkuv1alpha1.RokkuOIDC{IssuerURL: server.URL + testRealmPath}
+			err := checkOIDCDiscovery(context.Background(), server.Client(), oidc)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckOIDCTrustsCABundles(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		issuer := server.URL + testRealmPath
+		json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/certs"})
+	}))
+	defer server.Close()
+
+	caBundle := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "internal-ca", Namespace: "default"},
+		Data:       map[string]string{"ca.crt": caBundle},
+	}
+	rokku := &rokkuv1alpha1.Rokku{
+		ObjectMeta: metav1.ObjectMeta{Name: "rokku", Namespace: "default"},
+		Spec: rokkuv1alpha1.RokkuSpec{
+			STS: &rokkuv1alpha1.RokkuSTS{
+				OIDC: &rokkuv1alpha1.RokkuOIDC{IssuerURL: server.URL + testRealmPath},
+			},
+		},
+	}
+	r := newTestReconciler(cm)
+
+	if check := r.checkOIDC(context.Background(), rokku); check.err == nil {
+		t.Fatal("expected the issuer certificate to be rejected without the CA bundle")
+	}
+
+	rokku.Spec.Trust = &rokkuv1alpha1.RokkuTrust{
+		CABundles: []rokkuv1alpha1.RokkuCABundle{
+			{ConfigMap: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "internal-ca"},
+				Key:                  "ca.crt",
+			}},
+		},
+	}
+	if check := r.checkOIDC(context.Background(), rokku); check.err != nil {
+		t.Fatalf("unexpected error with the CA bundle: %v", check.err)
+	}
+}
+
+func TestCheckOIDCCachesOutcome(t *testing.T) {
+	requests := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		issuer := server.URL + testRealmPath
+		json.NewEncoder(w).Encode(map[string]string{"issuer": issuer, "jwks_uri": issuer + "/certs"})
+	}))
+	defer server.Close()
+
+	newRokku := func(name string) *rokkuv1alpha1.Rokku {
+		return &rokkuv1alpha1.Rokku{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: rokkuv1alpha1.RokkuSpec{
+				STS: &rokkuv1alpha1.RokkuSTS{
+					OIDC: &rokkuv1alpha1.RokkuOIDC{IssuerURL: server.URL + testRealmPath},
+				},
+			},
+		}
+	}
+	r := newTestReconciler()
+
+	for _, name := range []string{"rokku-a", "rokku-a", "rokku-b"} {
+		if check := r.checkOIDC(context.Background(), newRokku(name)); check.err != nil {
+			t.Fatalf("unexpected error: %v", check.err)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("expected the issuer to be checked once, got %d requests", requests)
+	}
+}
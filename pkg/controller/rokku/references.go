@@ -0,0 +1,73 @@
+package rokku
+
+import (
+	"context"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// referencesIndex indexes the Rokkus by the ConfigMaps and Secrets whose
+// content is hashed into their pod template, i.e. the config ConfigMap and
+// the CA bundles.
+const referencesIndex = "rokku.ing.com/references"
+
+func referenceKey(kind, name string) string {
+	return kind + "/" + name
+}
+
+// indexReferences registers referencesIndex into the manager cache.
+func indexReferences(mgr manager.Manager) error {
+	return mgr.GetFieldIndexer().IndexField(&rokkuv1alpha1.Rokku{}, referencesIndex, func(o runtime.Object) []string {
+		rokku := o.(*rokkuv1alpha1.Rokku)
+		var keys []string
+		if conf := rokku.Spec.Config; conf != nil && conf.Kind == rokkuv1alpha1.ConfigKindConfigMap {
+			keys = append(keys, referenceKey("ConfigMap", conf.Name))
+		}
+		if rokku.Spec.Trust != nil {
+			for _, bundle := range rokku.Spec.Trust.CABundles {
+				if bundle.ConfigMap != nil {
+					keys = append(keys, referenceKey("ConfigMap", bundle.ConfigMap.Name))
+				}
+				if bundle.Secret != nil {
+					keys = append(keys, referenceKey("Secret", bundle.Secret.Name))
+				}
+			}
+		}
+		return keys
+	})
+}
+
+// referencingRokkus enqueues the Rokkus referencing the ConfigMap or Secret.
+func referencingRokkus(c client.Client) handler.ToRequestsFunc {
+	return func(o handler.MapObject) []reconcile.Request {
+		kind := "ConfigMap"
+		if _, isSecret := o.Object.(*corev1.Secret); isSecret {
+			kind = "Secret"
+		}
+
+		var rokkus rokkuv1alpha1.RokkuList
+		err := c.List(context.Background(), &rokkus,
+			client.InNamespace(o.Meta.GetNamespace()),
+			client.MatchingFields{referencesIndex: referenceKey(kind, o.Meta.GetName())})
+		if err != nil {
+			log.Error(err, "Unable to list Rokku resources")
+			return nil
+		}
+
+		requests := make([]reconcile.Request, 0, len(rokkus.Items))
+		for _, rokku := range rokkus.Items {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+				Name:      rokku.Name,
+				Namespace: rokku.Namespace,
+			}})
+		}
+		return requests
+	}
+}
@@ -0,0 +1,151 @@
+package rokku
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"github.com/jwi078/rokku-operator/pkg/k8s"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const defaultRevisionHistoryLimit = 10
+
+// reconcileRollbackTo replaces the Rokku spec by the one stored in the
+// revision requested through spec.rollbackTo. It returns whether the Rokku
+// was updated, in which case the reconcile is resumed by the update event.
+func (r *ReconcileRokku) reconcileRollbackTo(ctx context.Context, rokku *rokkuv1alpha1.Rokku) (bool, error) {
+	if rokku.Spec.RollbackTo == nil {
+		return false, nil
+	}
+	target := *rokku.Spec.RollbackTo
+
+	revisions, err := listRevisions(ctx, r.client, rokku)
+	if err != nil {
+		return false, err
+	}
+
+	var revision *appv1.ControllerRevision
+	for i := range revisions {
+		if revisions[i].Revision == target {
+			revision = &revisions[i]
+			break
+		}
+	}
+
+	if revision == nil {
+		r.recorder.Eventf(rokku, corev1.EventTypeWarning, "RollbackRevisionNotFound", "revision %d not found, rollback skipped", target)
+		rokku.Spec.RollbackTo = nil
+	} else {
+		spec, err := k8s.ExtractRevisionSpec(revision)
+		if err != nil {
+			return false, err
+		}
+		// The number of replicas is left untouched as it may be managed by
+		// the scale subresource.
+		spec.Replicas = rokku.Spec.Replicas
+		spec.RollbackTo = nil
+		rokku.Spec = spec
+		r.recorder.Eventf(rokku, corev1.EventTypeNormal, "RolledBack", "rolled back to revision %d (%s)", target, revision.Name)
+	}
+
+	if err := r.client.Update(ctx, rokku); err != nil {
+		return false, fmt.Errorf("failed to roll rokku back to revision %d: %v", target, err)
+	}
+	return true, nil
+}
+
+// reconcileRevisions stores the spec submitted for the Rokku, before its
+// defaults and checksums are set, as a ControllerRevision, making it the
+// update revision, and prunes the revisions beyond the history limit.
+func (r *ReconcileRokku) reconcileRevisions(ctx context.Context, rokku *rokkuv1alpha1.Rokku, spec rokkuv1alpha1.RokkuSpec) error {
+	hash, err := k8s.SpecHash(spec)
+	if err != nil {
+		return fmt.Errorf("failed to hash rokku spec: %v", err)
+	}
+	name := k8s.RevisionName(rokku.Name, hash)
+
+	revisions, err := listRevisions(ctx, r.client, rokku)
+	if err != nil {
+		return err
+	}
+
+	var latest int64
+	var current *appv1.ControllerRevision
+	for i := range revisions {
+		if revisions[i].Revision > latest {
+			latest = revisions[i].Revision
+		}
+		if revisions[i].Name == name {
+			current = &revisions[i]
+		}
+	}
+
+	switch {
+	case current == nil:
+		rev, err := k8s.NewControllerRevision(rokku, spec, hash, latest+1)
+		if err != nil {
+			return fmt.Errorf("failed to assemble controller revision: %v", err)
+		}
+		if err := r.client.Create(ctx, rev); err != nil && !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create controller revision: %v", err)
+		}
+		revisions = append(revisions, *rev)
+	case current.Revision < latest:
+		// Re-applying an older spec makes its revision the latest one.
+		current.Revision = latest + 1
+		if err := r.client.Update(ctx, current); err != nil {
+			return fmt.Errorf("failed to update controller revision: %v", err)
+		}
+	}
+	rokku.Status.UpdateRevision = name
+
+	limit := defaultRevisionHistoryLimit
+	if rokku.Spec.RevisionHistoryLimit != nil {
+		limit = int(*rokku.Spec.RevisionHistoryLimit)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].Revision < revisions[j].Revision
+	})
+	for i := 0; i < len(revisions)-limit; i++ {
+		rev := revisions[i]
+		if rev.Name == rokku.Status.UpdateRevision || rev.Name == rokku.Status.CurrentRevision {
+			continue
+		}
+		if err := r.client.Delete(ctx, &rev); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to prune controller revision %s: %v", rev.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// markRevisionComplete records the update revision as fully rolled out.
+func markRevisionComplete(rokku *rokkuv1alpha1.Rokku) {
+	rokku.Status.CurrentRevision = rokku.Status.UpdateRevision
+}
+
+// listRevisions returns the ControllerRevisions of the given Rokku, leaving
+// out the ones of its DaemonSet.
+func listRevisions(ctx context.Context, c client.Client, rokku *rokkuv1alpha1.Rokku) ([]appv1.ControllerRevision, error) {
+	revisionList := &appv1.ControllerRevisionList{}
+	labelSelector := labels.SelectorFromSet(k8s.LabelsForRevisions(rokku.Name))
+	listOps := &client.ListOptions{Namespace: rokku.Namespace, LabelSelector: labelSelector}
+	if err := c.List(ctx, revisionList, listOps); err != nil {
+		return nil, fmt.Errorf("failed to list controller revisions: %v", err)
+	}
+
+	var revisions []appv1.ControllerRevision
+	for _, rev := range revisionList.Items {
+		if metav1.IsControlledBy(&rev, rokku) {
+			revisions = append(revisions, rev)
+		}
+	}
+	return revisions, nil
+}
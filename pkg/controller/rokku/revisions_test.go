@@ -0,0 +1,176 @@
+package rokku
+
+import (
+	"context"
+	"testing"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"github.com/jwi078/rokku-operator/pkg/k8s"
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// newTestRevision returns the ControllerRevision storing the given spec of
+// the given Rokku.
+func newTestRevision(t *testing.T, rokku *rokkuv1alpha1.Rokku, spec rokkuv1alpha1.RokkuSpec, revision int64) *appv1.ControllerRevision {
+	hash, err := k8s.SpecHash(spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rev, err := k8s.NewControllerRevision(rokku, spec, hash, revision)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return rev
+}
+
+// newDaemonSetRevision returns a ControllerRevision as created by the
+// DaemonSet controller for the Rokku DaemonSet, carrying the pod labels.
+func newDaemonSetRevision(name string, revision int64) *appv1.ControllerRevision {
+	controller := true
+	return &appv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    k8s.LabelsForRokku("rokku"),
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "DaemonSet", Name: "rokku", UID: "daemonset-uid", Controller: &controller},
+			},
+		},
+		Data:     runtime.RawExtension{Raw: []byte(`{"spec":{"template":{"metadata":{"labels":{"rokku.ing.com/app":"rokku"}}}}}`)},
+		Revision: revision,
+	}
+}
+
+func revisionNumbers(t *testing.T, r *ReconcileRokku, rokku *rokkuv1alpha1.Rokku) map[string]int64 {
+	revisions, err := listRevisions(context.Background(), r.client, rokku)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	numbers := make(map[string]int64)
+	for _, rev := range revisions {
+		numbers[rev.Name] = rev.Revision
+	}
+	return numbers
+}
+
+func TestListRevisions(t *testing.T) {
+	rokku := newTestRokku()
+	other := newTestRokku()
+	other.UID = "other-uid"
+
+	own := newTestRevision(t, rokku, rokkuv1alpha1.RokkuSpec{Image: "wbaa/rokku:1"}, 1)
+	foreign := newTestRevision(t, other, rokkuv1alpha1.RokkuSpec{Image: "wbaa/rokku:2"}, 2)
+	r := newTestReconciler(own, foreign, newDaemonSetRevision("rokku-5d8f7c9b4", 3))
+
+	if got := revisionNumbers(t, r, rokku); len(got) != 1 || got[own.Name] != 1 {
+		t.Errorf("revisions = %v, want only %s", got, own.Name)
+	}
+}
+
+func TestReconcileRevisions(t *testing.T) {
+	ctx := context.Background()
+	rokku := newTestRokku()
+	limit := int32(2)
+	rokku.Spec.RevisionHistoryLimit = &limit
+	daemonSetRevision := newDaemonSetRevision("rokku-5d8f7c9b4", 7)
+	r := newTestReconciler(daemonSetRevision)
+
+	apply := func(image string) string {
+		spec := rokku.Spec.DeepCopy()
+		spec.Image = image
+		if err := r.reconcileRevisions(ctx, rokku, *spec); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return rokku.Status.UpdateRevision
+	}
+
+	first := apply("wbaa/rokku:1")
+	second := apply("wbaa/rokku:2")
+	if got := revisionNumbers(t, r, rokku); got[first] != 1 || got[second] != 2 {
+		t.Fatalf("revisions = %v, want %s and %s numbered 1 and 2, ignoring the DaemonSet ones", got, first, second)
+	}
+
+	// Re-applying a spec makes its revision the latest one
+	if again := apply("wbaa/rokku:1"); again != first {
+		t.Fatalf("update revision = %s, want %s", again, first)
+	}
+	if got := revisionNumbers(t, r, rokku); got[first] != 3 {
+		t.Errorf("revisions = %v, want %s renumbered 3", got, first)
+	}
+
+	// Pruning keeps the history limit and never touches the DaemonSet
+	// revisions
+	rokku.Status.CurrentRevision = first
+	third := apply("wbaa/rokku:3")
+	if got := revisionNumbers(t, r, rokku); len(got) != 2 || got[first] != 3 || got[third] != 4 {
+		t.Errorf("revisions = %v, want %s and %s", got, first, third)
+	}
+	if err := r.client.Get(ctx, types.NamespacedName{Name: daemonSetRevision.Name, Namespace: "default"}, &appv1.ControllerRevision{}); err != nil {
+		t.Errorf("DaemonSet revision must be kept: %v", err)
+	}
+}
+
+func TestReconcileRollbackTo(t *testing.T) {
+	ctx := context.Background()
+	three := int32(3)
+	submitted := rokkuv1alpha1.RokkuSpec{
+		Config: &rokkuv1alpha1.ConfigRef{Kind: rokkuv1alpha1.ConfigKindConfigMap, Name: "rokku-config"},
+	}
+
+	tests := []struct {
+		name       string
+		rollbackTo int64
+		wantSpec   rokkuv1alpha1.RokkuSpec
+	}{
+		{
+			name:       "stored revision",
+			rollbackTo: 1,
+			// The stored spec is restored as submitted, without defaults
+			// nor checksums, keeping the current number of replicas
+			wantSpec: rokkuv1alpha1.RokkuSpec{Config: submitted.Config, Replicas: &three},
+		},
+		{
+			name:       "DaemonSet revision",
+			rollbackTo: 2,
+			wantSpec:   rokkuv1alpha1.RokkuSpec{Image: "wbaa/rokku:2", Replicas: &three},
+		},
+		{
+			name:       "unknown revision",
+			rollbackTo: 5,
+			wantSpec:   rokkuv1alpha1.RokkuSpec{Image: "wbaa/rokku:2", Replicas: &three},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rokku := newTestRokku()
+			rokku.Spec = rokkuv1alpha1.RokkuSpec{Image: "wbaa/rokku:2", Replicas: &three, RollbackTo: &tt.rollbackTo}
+			r := newTestReconciler(rokku.DeepCopy(), newTestRevision(t, rokku, submitted, 1), newDaemonSetRevision("rokku-5d8f7c9b4", 2))
+
+			rolledBack, err := r.reconcileRollbackTo(ctx, rokku)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !rolledBack {
+				t.Fatal("expected the Rokku to be updated")
+			}
+
+			var got rokkuv1alpha1.Rokku
+			if err := r.client.Get(ctx, types.NamespacedName{Name: "rokku", Namespace: "default"}, &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Spec.RollbackTo != nil {
+				t.Errorf("rollbackTo = %d, want it cleared", *got.Spec.RollbackTo)
+			}
+			if got.Spec.Image != tt.wantSpec.Image || *got.Spec.Replicas != *tt.wantSpec.Replicas || (got.Spec.Config == nil) != (tt.wantSpec.Config == nil) {
+				t.Errorf("spec = %+v, want %+v", got.Spec, tt.wantSpec)
+			}
+			if got.Spec.PodTemplate.Annotations != nil || got.Spec.PodTemplate.Ports != nil {
+				t.Errorf("pod template = %+v, want the submitted one", got.Spec.PodTemplate)
+			}
+		})
+	}
+}
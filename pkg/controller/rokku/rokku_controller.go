@@ -5,15 +5,21 @@ import (
 	"fmt"
 	"reflect"
 	"sort"
+	"sync"
+	"time"
 
 	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
 	"github.com/jwi078/rokku-operator/pkg/k8s"
 	appv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -33,7 +39,12 @@ func Add(mgr manager.Manager) error {
 
 // newReconciler returns a new reconcile.Reconciler
 func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileRokku{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+	return &ReconcileRokku{
+		client:    mgr.GetClient(),
+		scheme:    mgr.GetScheme(),
+		discovery: memory.NewMemCacheClient(discovery.NewDiscoveryClientForConfigOrDie(mgr.GetConfig())),
+		recorder:  mgr.GetEventRecorderFor("rokku-controller"),
+	}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
@@ -50,6 +61,38 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return err
 	}
 
+	// Watch for changes to the Deployments and DaemonSets owned by a Rokku, so
+	// that rollouts are driven by their progress
+	err = c.Watch(&source.Kind{Type: &appv1.Deployment{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &rokkuv1alpha1.Rokku{},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = c.Watch(&source.Kind{Type: &appv1.DaemonSet{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &rokkuv1alpha1.Rokku{},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to the config ConfigMap and the CA bundles, so that
+	// the pods are replaced with an up to date config and truststore
+	if err := indexReferences(mgr); err != nil {
+		return err
+	}
+	for _, obj := range []runtime.Object{&corev1.ConfigMap{}, &corev1.Secret{}} {
+		err = c.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: referencingRokkus(mgr.GetClient()),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	// HACK(nettoclaudio): Since the Rokku needs store all its pods' info into
 	// the status field, we need watching every pod changes and enqueue a new
 	// reconcile request to its Rokku owner, if any.
@@ -80,6 +123,16 @@ type ReconcileRokku struct {
 	// that reads objects from the cache and writes to the apiserver
 	client client.Client
 	scheme *runtime.Scheme
+	// discovery is used to check whether optional APIs, such as the
+	// prometheus-operator ones, are served by the cluster. Its results are
+	// cached and refreshed every discoveryTTL.
+	discovery          discovery.CachedDiscoveryInterface
+	discoveryMu        sync.Mutex
+	discoveryRefreshed time.Time
+	// oidcChecks caches the outcome of the OIDC discovery checks
+	oidcChecks map[string]oidcCheck
+	oidcMu     sync.Mutex
+	recorder   record.EventRecorder
 }
 
 // Reconcile reads that state of the cluster for a Rokku object and makes changes based on the state read
@@ -106,59 +159,229 @@ func (r *ReconcileRokku) Reconcile(request reconcile.Request) (reconcile.Result,
 		return reconcile.Result{}, err
 	}
 
-	if err := r.reconcileRokku(ctx, instance); err != nil {
+	if k8s.IsRokkuPaused(instance) {
+		reqLogger.Info("Rokku reconciliation is paused, only refreshing status")
+		originalStatus := instance.Status.DeepCopy()
+		if !isConditionTrue(instance.Status, rokkuv1alpha1.RokkuPaused) {
+			r.recorder.Event(instance, corev1.EventTypeNormal, "Paused", "reconciliation paused")
+		}
+		setCondition(&instance.Status, rokkuv1alpha1.RokkuPaused, corev1.ConditionTrue, "ReconcilePaused", "changes to the Rokku children are not applied")
+		if err := r.refreshStatus(ctx, instance, originalStatus); err != nil {
+			reqLogger.Error(err, "Fail to refresh status subresource")
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, nil
+	}
+
+	rolledBack, err := r.reconcileRollbackTo(ctx, instance)
+	if err != nil {
+		reqLogger.Error(err, "Fail to roll back")
+		return reconcile.Result{}, err
+	}
+	if rolledBack {
+		return reconcile.Result{}, nil
+	}
+
+	originalStatus := instance.Status.DeepCopy()
+
+	if isConditionTrue(instance.Status, rokkuv1alpha1.RokkuPaused) {
+		r.recorder.Event(instance, corev1.EventTypeNormal, "Resumed", "reconciliation resumed")
+		setCondition(&instance.Status, rokkuv1alpha1.RokkuPaused, corev1.ConditionFalse, "ReconcileResumed", "")
+	}
+
+	// Revisions store the spec as submitted, so that rolling back to one of
+	// them neither writes the defaults nor stale checksums into the Rokku
+	submittedSpec := instance.Spec.DeepCopy()
+
+	k8s.SetRokkuDefaults(instance)
+	if err := r.refreshTrustChecksum(ctx, instance); err != nil {
+		reqLogger.Error(err, "Fail to refresh CA bundles checksum")
+		return reconcile.Result{}, err
+	}
+	if err := r.refreshConfigChecksum(ctx, instance); err != nil {
+		reqLogger.Error(err, "Fail to refresh config checksum")
+		return reconcile.Result{}, err
+	}
+	if err := k8s.ValidateRokku(instance); err != nil {
+		// Retrying can't fix an invalid spec, the next reconcile is triggered
+		// by its update
+		reqLogger.Info("Invalid Rokku spec", "Reason", err.Error())
+		if cond := getCondition(instance.Status, rokkuv1alpha1.RokkuValid); cond == nil || cond.Status != corev1.ConditionFalse || cond.Message != err.Error() {
+			r.recorder.Event(instance, corev1.EventTypeWarning, "InvalidSpec", err.Error())
+		}
+		setCondition(&instance.Status, rokkuv1alpha1.RokkuValid, corev1.ConditionFalse, "InvalidSpec", err.Error())
+		if err := r.refreshStatus(ctx, instance, originalStatus); err != nil {
+			reqLogger.Error(err, "Fail to refresh status subresource")
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, nil
+	}
+	setCondition(&instance.Status, rokkuv1alpha1.RokkuValid, corev1.ConditionTrue, "SpecValid", "")
+
+	if err := r.reconcileRevisions(ctx, instance, *submittedSpec); err != nil {
+		reqLogger.Error(err, "Fail to reconcile revisions")
+		return reconcile.Result{}, err
+	}
+
+	result, err := r.reconcileRokku(ctx, instance)
+	if err != nil {
 		reqLogger.Error(err, "Fail to reconcile")
 		return reconcile.Result{}, err
 	}
 
-	if err := r.refreshStatus(ctx, instance); err != nil {
+	if err := r.refreshStatus(ctx, instance, originalStatus); err != nil {
 		reqLogger.Error(err, "Fail to refresh status subresource")
 		return reconcile.Result{}, err
 	}
 
-	return reconcile.Result{}, nil
+	return result, nil
 }
 
-func (r *ReconcileRokku) reconcileRokku(ctx context.Context, rokku *rokkuv1alpha1.Rokku) error {
-	if err := r.reconcileDeployment(ctx, rokku); err != nil {
-		return err
+func (r *ReconcileRokku) reconcileRokku(ctx context.Context, rokku *rokkuv1alpha1.Rokku) (reconcile.Result, error) {
+	if err := refreshSecurityViolations(rokku); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// The pods can't be created before their ServiceAccount
+	if err := r.reconcileServiceAccount(ctx, rokku); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	oidcRequeueAfter := r.reconcileOIDC(ctx, rokku)
+
+	stsReady, err := r.reconcileSTS(ctx, rokku)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// Rokku is only rolled out for the first time once the managed rokku-sts
+	// it authenticates requests with is ready, the sts Deployment watch
+	// triggers the next reconcile. Afterwards, an unready rokku-sts is only
+	// reported, so that it never blocks the Rokku workload.
+	rollOut := stsReady
+	if !rollOut {
+		rollOut, err = r.workloadExists(ctx, rokku)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	var result reconcile.Result
+	if rollOut {
+		result, err = r.reconcileDeployment(ctx, rokku)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if err := r.reconcileMaintenance(ctx, rokku); err != nil {
+		return result, err
 	}
 
 	if err := r.reconcileService(ctx, rokku); err != nil {
-		return err
+		return result, err
 	}
 
-	return nil
+	if err := r.reconcileNetworkPolicy(ctx, rokku); err != nil {
+		return result, err
+	}
+
+	if err := r.reconcileMonitor(ctx, rokku); err != nil {
+		return result, err
+	}
+
+	if err := r.reconcilePrometheusRule(ctx, rokku); err != nil {
+		return result, err
+	}
+
+	if oidcRequeueAfter > 0 && (result.RequeueAfter == 0 || oidcRequeueAfter < result.RequeueAfter) {
+		result.RequeueAfter = oidcRequeueAfter
+	}
+	return result, nil
 }
 
-func (r *ReconcileRokku) reconcileDeployment(ctx context.Context, rokku *rokkuv1alpha1.Rokku) error {
+func (r *ReconcileRokku) reconcileDeployment(ctx context.Context, rokku *rokkuv1alpha1.Rokku) (reconcile.Result, error) {
+	if err := r.loadActiveColor(ctx, rokku); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if rokku.Spec.WorkloadKind == rokkuv1alpha1.WorkloadKindDaemonSet {
+		return r.reconcileDaemonSet(ctx, rokku)
+	}
+
+	if rokku.Spec.PodTemplate.HostNetwork {
+		if err := r.deleteHostNetworkWorkload(ctx, rokku, rokku.Name, &appv1.DaemonSet{}); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	if rokku.Spec.Rollout != nil && rokku.Spec.Rollout.Strategy == rokkuv1alpha1.RolloutStrategyBlueGreen {
+		return r.reconcileBlueGreen(ctx, rokku)
+	}
+
 	newDeploy, err := k8s.NewDeployment(rokku)
 	if err != nil {
-		return fmt.Errorf("failed to assemble deployment from Rokku: %v", err)
+		return reconcile.Result{}, fmt.Errorf("failed to assemble deployment from Rokku: %v", err)
 	}
 
 	err = r.client.Create(ctx, newDeploy)
 	if err != nil && !errors.IsAlreadyExists(err) {
-		return fmt.Errorf("failed to create deployment: %v", err)
+		return reconcile.Result{}, fmt.Errorf("failed to create deployment: %v", err)
 	}
 
 	if err == nil {
-		return nil
+		return reconcile.Result{}, nil
 	}
 
 	currDeploy := &appv1.Deployment{}
 
 	err = r.client.Get(ctx, types.NamespacedName{Name: newDeploy.Name, Namespace: newDeploy.Namespace}, currDeploy)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve deployment: %v", err)
+		return reconcile.Result{}, fmt.Errorf("failed to retrieve deployment: %v", err)
 	}
 
 	currSpec, err := k8s.ExtractRokkuSpec(currDeploy.ObjectMeta)
 	if err != nil {
-		return fmt.Errorf("failed to extract rokku from deployment: %v", err)
+		return reconcile.Result{}, fmt.Errorf("failed to extract rokku from deployment: %v", err)
+	}
+
+	rokku.Status.Workload = deploymentWorkloadStatus(currDeploy)
+
+	if err := r.deleteDaemonSet(ctx, rokku, currDeploy); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if err := r.cleanupBlueGreen(ctx, rokku, currDeploy); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if rokku.Spec.Rollout != nil && rokku.Spec.Rollout.Strategy == rokkuv1alpha1.RolloutStrategyCanary {
+		return r.reconcileCanary(ctx, rokku, currDeploy, currSpec, newDeploy)
+	}
+
+	if err := r.deleteCanary(ctx, rokku); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	rolledBack, err := isRolledBack(rokku)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if rolledBack {
+		return reconcile.Result{}, nil
+	}
+	rokku.Status.Rollout = nil
+
+	if err := r.updateDeployment(ctx, rokku, currDeploy, currSpec, newDeploy); err != nil {
+		return reconcile.Result{}, err
 	}
 
-	if reflect.DeepEqual(rokku.Spec, currSpec) {
+	return reconcile.Result{}, r.checkRollingUpdate(ctx, rokku, currDeploy)
+}
+
+// updateDeployment updates currDeploy to newDeploy when the Rokku spec it was
+// generated from, or its number of replicas, has changed.
+func (r *ReconcileRokku) updateDeployment(ctx context.Context, rokku *rokkuv1alpha1.Rokku, currDeploy *appv1.Deployment, currSpec rokkuv1alpha1.RokkuSpec, newDeploy *appv1.Deployment) error {
+	if reflect.DeepEqual(rokku.Spec, currSpec) && reflect.DeepEqual(currDeploy.Spec.Replicas, newDeploy.Spec.Replicas) {
 		return nil
 	}
 
@@ -176,17 +399,20 @@ func (r *ReconcileRokku) reconcileDeployment(ctx context.Context, rokku *rokkuv1
 
 func (r *ReconcileRokku) reconcileService(ctx context.Context, rokku *rokkuv1alpha1.Rokku) error {
 	svcName := types.NamespacedName{
-		Name:      fmt.Sprintf("%s-service", rokku.Name),
+		Name:      k8s.ServiceName(rokku.Name),
 		Namespace: rokku.Namespace,
 	}
 
 	logger := log.WithName("reconcileService").WithValues("Service", svcName)
 	logger.V(4).Info("Getting Service resource")
 
-	newService := k8s.NewService(rokku)
+	newService, err := k8s.NewService(rokku)
+	if err != nil {
+		return fmt.Errorf("failed to assemble service from Rokku: %v", err)
+	}
 
 	var currentService corev1.Service
-	err := r.client.Get(ctx, svcName, &currentService)
+	err = r.client.Get(ctx, svcName, &currentService)
 	if err != nil && errors.IsNotFound(err) {
 		logger.
 			WithValues("ServiceResource", newService).V(4).Info("Creating a Service resource")
@@ -216,8 +442,51 @@ func (r *ReconcileRokku) reconcileService(ctx context.Context, rokku *rokkuv1alp
 	return r.client.Update(ctx, newService)
 }
 
-func (r *ReconcileRokku) refreshStatus(ctx context.Context, rokku *rokkuv1alpha1.Rokku) error {
-	pods, err := listPods(ctx, r.client, rokku)
+func (r *ReconcileRokku) reconcileNetworkPolicy(ctx context.Context, rokku *rokkuv1alpha1.Rokku) error {
+	npName := types.NamespacedName{
+		Name:      k8s.NetworkPolicyName(rokku.Name),
+		Namespace: rokku.Namespace,
+	}
+
+	logger := log.WithName("reconcileNetworkPolicy").WithValues("NetworkPolicy", npName)
+
+	var currentNP networkingv1.NetworkPolicy
+	err := r.client.Get(ctx, npName, &currentNP)
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to retrieve NetworkPolicy resource: %v", err)
+	}
+	found := err == nil
+
+	if rokku.Spec.NetworkPolicy == nil {
+		if !found {
+			return nil
+		}
+		logger.V(4).Info("Deleting NetworkPolicy resource")
+		if err := r.client.Delete(ctx, &currentNP); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete NetworkPolicy resource: %v", err)
+		}
+		return nil
+	}
+
+	newNP := k8s.NewNetworkPolicy(rokku)
+	if !found {
+		logger.WithValues("NetworkPolicyResource", newNP).V(4).Info("Creating a NetworkPolicy resource")
+		return r.client.Create(ctx, newNP)
+	}
+
+	if reflect.DeepEqual(newNP.Spec, currentNP.Spec) {
+		return nil
+	}
+
+	newNP.ResourceVersion = currentNP.ResourceVersion
+	logger.WithValues("NetworkPolicyResource", newNP).V(4).Info("Updating NetworkPolicy resource")
+	return r.client.Update(ctx, newNP)
+}
+
+// refreshStatus fills in the observed state of the Rokku children and writes
+// the status when it differs from originalStatus.
+func (r *ReconcileRokku) refreshStatus(ctx context.Context, rokku *rokkuv1alpha1.Rokku, originalStatus *rokkuv1alpha1.RokkuStatus) error {
+	pods, summary, err := listPods(ctx, r.client, rokku)
 	if err != nil {
 		return fmt.Errorf("failed to list pods for Rokku: %v", err)
 	}
@@ -226,20 +495,33 @@ func (r *ReconcileRokku) refreshStatus(ctx context.Context, rokku *rokkuv1alpha1
 		return fmt.Errorf("failed to list services for rokku: %v", err)
 
 	}
+	hosts, err := r.listHosts(ctx, rokku)
+	if err != nil {
+		return fmt.Errorf("failed to list ingress hosts for rokku: %v", err)
+	}
+	for i := range services {
+		for _, h := range hosts[services[i].Name] {
+			services[i].Hosts = append(services[i].Hosts, h.host)
+		}
+	}
+	endpoint := rokkuEndpoint(rokku, services, hosts)
+
+	rokku.Status.Pods = pods
+	rokku.Status.PodsSummary = summary
+	rokku.Status.Services = services
+	rokku.Status.Endpoint = endpoint
+	rokku.Status.CurrentReplicas = summary.Total
+	rokku.Status.PodSelector = k8s.LabelsForRokkuString(rokku.Name)
 
-	sort.Slice(rokku.Status.Pods, func(i, j int) bool {
-		return rokku.Status.Pods[i].Name < rokku.Status.Pods[j].Name
+	sort.Slice(originalStatus.Pods, func(i, j int) bool {
+		return originalStatus.Pods[i].Name < originalStatus.Pods[j].Name
 	})
 
-	sort.Slice(rokku.Status.Services, func(i, j int) bool {
-		return rokku.Status.Services[i].Name < rokku.Status.Services[j].Name
+	sort.Slice(originalStatus.Services, func(i, j int) bool {
+		return originalStatus.Services[i].Name < originalStatus.Services[j].Name
 	})
 
-	if !reflect.DeepEqual(pods, rokku.Status.Pods) || !reflect.DeepEqual(services, rokku.Status.Services) {
-		rokku.Status.Pods = pods
-		rokku.Status.Services = services
-		rokku.Status.CurrentReplicas = int32(len(pods))
-		rokku.Status.PodSelector = k8s.LabelsForRokkuString(rokku.Name)
+	if !reflect.DeepEqual(&rokku.Status, originalStatus) {
 		err := r.client.Status().Update(ctx, rokku)
 		if err != nil {
 			return fmt.Errorf("failed to update rokku status: %v", err)
@@ -249,17 +531,24 @@ func (r *ReconcileRokku) refreshStatus(ctx context.Context, rokku *rokkuv1alpha1
 	return nil
 }
 
-// listPods return all the pods for the given rokku sorted by name
-func listPods(ctx context.Context, c client.Client, rokku *rokkuv1alpha1.Rokku) ([]rokkuv1alpha1.PodStatus, error) {
+// maxPodStatuses is the number of pods above which only the pods summary is
+// kept in the status, keeping the Rokku object small for large replica counts.
+const maxPodStatuses = 25
+
+// listPods return all the pods for the given rokku sorted by name, along with
+// a summary of them. The per-pod list is nil when there are more than
+// maxPodStatuses pods.
+func listPods(ctx context.Context, c client.Client, rokku *rokkuv1alpha1.Rokku) ([]rokkuv1alpha1.PodStatus, *rokkuv1alpha1.PodsSummary, error) {
 	podList := &corev1.PodList{}
 	labelSelector := labels.SelectorFromSet(k8s.LabelsForRokku(rokku.Name))
 	listOps := &client.ListOptions{Namespace: rokku.Namespace, LabelSelector: labelSelector}
 	err := c.List(ctx, podList, listOps)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var pods []rokkuv1alpha1.PodStatus
+	summary := &rokkuv1alpha1.PodsSummary{}
 
 	for _, p := range podList.Items {
 		if p.Status.PodIP == "" {
@@ -270,17 +559,59 @@ func listPods(ctx context.Context, c client.Client, rokku *rokkuv1alpha1.Rokku)
 			p.Status.HostIP = "<pending>"
 		}
 
-		pods = append(pods, rokkuv1alpha1.PodStatus{
-			Name:   p.Name,
-			PodIP:  p.Status.PodIP,
-			HostIP: p.Status.HostIP,
-		})
+		status := rokkuv1alpha1.PodStatus{
+			Name:           p.Name,
+			PodIP:          p.Status.PodIP,
+			HostIP:         p.Status.HostIP,
+			Phase:          p.Status.Phase,
+			Ready:          isPodReady(&p),
+			NodeName:       p.Spec.NodeName,
+			StartTime:      p.Status.StartTime,
+			ConfigChecksum: k8s.GetConfigChecksumFromObject(&p),
+		}
+		for _, cs := range p.Status.ContainerStatuses {
+			if cs.Name != "rokku" {
+				continue
+			}
+			status.RestartCount = cs.RestartCount
+			status.Image = cs.Image
+			status.ImageID = cs.ImageID
+			if cs.LastTerminationState.Terminated != nil {
+				status.LastTerminationReason = cs.LastTerminationState.Terminated.Reason
+			}
+		}
+
+		summary.Total++
+		summary.Restarts += status.RestartCount
+		if status.Ready {
+			summary.Ready++
+		}
+		if summary.Phases == nil {
+			summary.Phases = make(map[corev1.PodPhase]int32)
+		}
+		summary.Phases[status.Phase]++
+
+		pods = append(pods, status)
 	}
+
+	if len(pods) > maxPodStatuses {
+		return nil, summary, nil
+	}
+
 	sort.Slice(pods, func(i, j int) bool {
 		return pods[i].Name < pods[j].Name
 	})
 
-	return pods, nil
+	return pods, summary, nil
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
 }
 
 // listServices return all the services for the given rokku sorted by name
@@ -295,9 +626,26 @@ func listServices(ctx context.Context, c client.Client, rokku *rokkuv1alpha1.Rok
 
 	var services []rokkuv1alpha1.ServiceStatus
 	for _, s := range serviceList.Items {
-		services = append(services, rokkuv1alpha1.ServiceStatus{
-			Name: s.Name,
-		})
+		status := rokkuv1alpha1.ServiceStatus{
+			Name:      s.Name,
+			DNSName:   k8s.ServiceDNSName(s.Name, s.Namespace),
+			ClusterIP: s.Spec.ClusterIP,
+		}
+		for _, port := range s.Spec.Ports {
+			status.Ports = append(status.Ports, rokkuv1alpha1.ServicePortStatus{
+				Name:     port.Name,
+				Port:     port.Port,
+				NodePort: port.NodePort,
+			})
+		}
+		for _, ingress := range s.Status.LoadBalancer.Ingress {
+			address := ingress.Hostname
+			if address == "" {
+				address = ingress.IP
+			}
+			status.LoadBalancerIngress = append(status.LoadBalancerIngress, address)
+		}
+		services = append(services, status)
 	}
 
 	sort.Slice(services, func(i, j int) bool {
@@ -0,0 +1,122 @@
+package rokku
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/jwi078/rokku-operator/pkg/apis"
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery/cached/memory"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// newTestReconciler returns a ReconcileRokku backed by a fake client holding
+// the given objects. Its discovery only serves the built-in APIs.
+func newTestReconciler(objs ...runtime.Object) *ReconcileRokku {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		panic(err)
+	}
+	if err := apis.AddToScheme(s); err != nil {
+		panic(err)
+	}
+	if err := monitoringv1.AddToScheme(s); err != nil {
+		panic(err)
+	}
+	builtin := []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "services", Kind: "Service"}}},
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{{Name: "deployments", Kind: "Deployment"}}},
+	}
+	return &ReconcileRokku{
+		client:    fake.NewFakeClientWithScheme(s, objs...),
+		scheme:    s,
+		discovery: memory.NewMemCacheClient(&fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{Resources: builtin}}),
+		recorder:  record.NewFakeRecorder(100),
+	}
+}
+
+// newTestRokku returns a Rokku with an empty spec, as submitted before its
+// defaults are set.
+func newTestRokku() *rokkuv1alpha1.Rokku {
+	return &rokkuv1alpha1.Rokku{
+		ObjectMeta: metav1.ObjectMeta{Name: "rokku", Namespace: "default", UID: "rokku-uid"},
+	}
+}
+
+// reconcileRokku runs a reconcile of the test Rokku and returns it as found
+// afterwards.
+func reconcileRokku(t *testing.T, r *ReconcileRokku) *rokkuv1alpha1.Rokku {
+	name := types.NamespacedName{Name: "rokku", Namespace: "default"}
+	if _, err := r.Reconcile(reconcile.Request{NamespacedName: name}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rokku := &rokkuv1alpha1.Rokku{}
+	if err := r.client.Get(context.Background(), name, rokku); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return rokku
+}
+
+// nextEvent returns the next event recorded by the test reconciler, if any.
+func nextEvent(r *ReconcileRokku) string {
+	select {
+	case event := <-r.recorder.(*record.FakeRecorder).Events:
+		return event
+	default:
+		return ""
+	}
+}
+
+func TestReconcilePaused(t *testing.T) {
+	ctx := context.Background()
+	rokku := newTestRokku()
+	rokku.Spec.Paused = true
+	r := newTestReconciler(rokku)
+	deployName := types.NamespacedName{Name: "rokku", Namespace: "default"}
+
+	paused := reconcileRokku(t, r)
+	if cond := getCondition(paused.Status, rokkuv1alpha1.RokkuPaused); cond == nil || cond.Status != corev1.ConditionTrue {
+		t.Errorf("paused condition = %+v, want true", cond)
+	}
+	if event := nextEvent(r); !strings.Contains(event, "Paused") {
+		t.Errorf("event = %q, want Paused", event)
+	}
+	err := r.client.Get(ctx, deployName, &appv1.Deployment{})
+	if !errors.IsNotFound(err) {
+		t.Fatalf("no Deployment must be created while paused, got %v", err)
+	}
+
+	// Reconciling again neither records another event nor changes anything
+	reconcileRokku(t, r)
+	if event := nextEvent(r); event != "" {
+		t.Errorf("unexpected event %q", event)
+	}
+
+	paused.Spec.Paused = false
+	if err := r.client.Update(ctx, paused); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resumed := reconcileRokku(t, r)
+	if cond := getCondition(resumed.Status, rokkuv1alpha1.RokkuPaused); cond == nil || cond.Status != corev1.ConditionFalse {
+		t.Errorf("paused condition = %+v, want false", cond)
+	}
+	if event := nextEvent(r); !strings.Contains(event, "Resumed") {
+		t.Errorf("event = %q, want Resumed", event)
+	}
+	if err := r.client.Get(ctx, deployName, &appv1.Deployment{}); err != nil {
+		t.Errorf("expected the Deployment once resumed: %v", err)
+	}
+}
@@ -0,0 +1,638 @@
+package rokku
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"github.com/jwi078/rokku-operator/pkg/k8s"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	defaultProgressDeadline = 600 * time.Second
+	defaultCanaryPause      = 60 * time.Second
+	defaultRollbackWindow   = time.Hour
+	defaultMaxErrorRate     = 0.05
+	canaryPollInterval      = 10 * time.Second
+)
+
+var defaultCanarySteps = []int32{25, 50}
+
+// reconcileCanary rolls the Rokku spec out through a canary Deployment. The
+// stable Deployment keeps running the previous spec, scaled down by the number
+// of canary replicas, until every step passes its gates and the canary is
+// promoted.
+func (r *ReconcileRokku) reconcileCanary(ctx context.Context, rokku *rokkuv1alpha1.Rokku, stable *appv1.Deployment, stableSpec rokkuv1alpha1.RokkuSpec, newDeploy *appv1.Deployment) (reconcile.Result, error) {
+	logger := log.WithName("reconcileCanary").WithValues("Rokku", types.NamespacedName{Name: rokku.Name, Namespace: rokku.Namespace})
+
+	hash, err := k8s.SpecHash(rokku.Spec)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to hash rokku spec: %v", err)
+	}
+	stableHash, err := k8s.SpecHash(stableSpec)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to hash rokku spec: %v", err)
+	}
+
+	total := *k8s.DeploymentReplicas(rokku)
+
+	// Nothing to roll out, the stable Deployment may only need to be scaled.
+	if hash == stableHash {
+		if err := r.deleteCanary(ctx, rokku); err != nil {
+			return reconcile.Result{}, err
+		}
+		if deploymentReady(stable) {
+			markRevisionComplete(rokku)
+		}
+		return reconcile.Result{}, r.updateDeployment(ctx, rokku, stable, stableSpec, newDeploy)
+	}
+
+	rollout := rokku.Status.Rollout
+	if rollout != nil && rollout.SpecHash == hash && rollout.Phase == rokkuv1alpha1.RolloutAborted {
+		if err := r.deleteCanary(ctx, rokku); err != nil {
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, r.scaleDeployment(ctx, stable, total)
+	}
+
+	if rollout == nil || rollout.SpecHash != hash || rollout.Phase != rokkuv1alpha1.RolloutProgressing {
+		now := metav1.Now()
+		rollout = &rokkuv1alpha1.RolloutStatus{
+			Phase:         rokkuv1alpha1.RolloutProgressing,
+			SpecHash:      hash,
+			StepStartTime: &now,
+		}
+		rokku.Status.Rollout = rollout
+		logger.Info("Starting canary rollout", "SpecHash", hash)
+	}
+
+	steps := defaultCanarySteps
+	pause := defaultCanaryPause
+	if canary := rokku.Spec.Rollout.Canary; canary != nil {
+		if len(canary.Steps) > 0 {
+			steps = canary.Steps
+		}
+		if canary.PauseSeconds != nil {
+			pause = time.Duration(*canary.PauseSeconds) * time.Second
+		}
+	}
+	if int(rollout.Step) >= len(steps) {
+		rollout.Step = int32(len(steps) - 1)
+	}
+
+	canaryReplicas := int32(math.Ceil(float64(total) * float64(steps[rollout.Step]) / 100))
+	if canaryReplicas < 1 {
+		canaryReplicas = 1
+	}
+	if canaryReplicas > total {
+		canaryReplicas = total
+	}
+
+	rollout.Message = fmt.Sprintf("step %d/%d: %d of %d replicas running the canary", rollout.Step+1, len(steps), canaryReplicas, total)
+	setCondition(&rokku.Status, rokkuv1alpha1.RokkuProgressing, corev1.ConditionTrue, "CanaryProgressing", rollout.Message)
+
+	canary, err := k8s.NewCanaryDeployment(rokku, canaryReplicas)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to assemble canary deployment from Rokku: %v", err)
+	}
+	currCanary, err := r.applyDeployment(ctx, canary)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := r.scaleDeployment(ctx, stable, total-canaryReplicas); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	deadline := defaultProgressDeadline
+	if d := rokku.Spec.Rollout.ProgressDeadlineSeconds; d != nil {
+		deadline = time.Duration(*d) * time.Second
+	}
+	elapsed := time.Since(rollout.StepStartTime.Time)
+
+	if !deploymentReady(currCanary) {
+		if elapsed > deadline {
+			return r.abortCanary(ctx, rokku, stable, fmt.Sprintf("canary pods not ready after %s", deadline))
+		}
+		return reconcile.Result{RequeueAfter: canaryPollInterval}, nil
+	}
+
+	if analysis := rokku.Spec.Rollout.Canary; analysis != nil && analysis.Analysis != nil {
+		maxRate := defaultMaxErrorRate
+		if analysis.Analysis.MaxErrorRate != "" {
+			maxRate, err = strconv.ParseFloat(analysis.Analysis.MaxErrorRate, 64)
+			if err != nil {
+				return reconcile.Result{}, fmt.Errorf("invalid canary max error rate %q: %v", analysis.Analysis.MaxErrorRate, err)
+			}
+		}
+		rate, err := queryPrometheus(ctx, analysis.Analysis.PrometheusURL, k8s.CanaryErrorRateQuery(rokku))
+		if err != nil {
+			logger.Error(err, "Unable to check the canary error rate")
+			if elapsed > deadline {
+				return r.abortCanary(ctx, rokku, stable, fmt.Sprintf("unable to check the canary error rate: %v", err))
+			}
+			return reconcile.Result{RequeueAfter: canaryPollInterval}, nil
+		}
+		if rate > maxRate {
+			return r.abortCanary(ctx, rokku, stable, fmt.Sprintf("canary error rate %.4f is above %.4f", rate, maxRate))
+		}
+	}
+
+	if elapsed < pause {
+		return reconcile.Result{RequeueAfter: pause - elapsed}, nil
+	}
+
+	if int(rollout.Step)+1 < len(steps) {
+		now := metav1.Now()
+		rollout.Step++
+		rollout.StepStartTime = &now
+		logger.Info("Canary step passed", "Step", rollout.Step)
+		return reconcile.Result{Requeue: true}, nil
+	}
+
+	return r.promoteCanary(ctx, rokku, stable, stableSpec, newDeploy)
+}
+
+// promoteCanary rolls the canary spec out to the stable Deployment and removes
+// the canary one.
+func (r *ReconcileRokku) promoteCanary(ctx context.Context, rokku *rokkuv1alpha1.Rokku, stable *appv1.Deployment, stableSpec rokkuv1alpha1.RokkuSpec, newDeploy *appv1.Deployment) (reconcile.Result, error) {
+	if err := r.updateDeployment(ctx, rokku, stable, stableSpec, newDeploy); err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := r.deleteCanary(ctx, rokku); err != nil {
+		return reconcile.Result{}, err
+	}
+	now := metav1.Now()
+	rokku.Status.Rollout.Phase = rokkuv1alpha1.RolloutPromoted
+	rokku.Status.Rollout.Message = "canary promoted"
+	rokku.Status.Rollout.StepStartTime = nil
+	rokku.Status.Rollout.CompletionTime = &now
+	markRevisionComplete(rokku)
+	setCondition(&rokku.Status, rokkuv1alpha1.RokkuProgressing, corev1.ConditionTrue, "CanaryPromoted", "canary promoted to all the replicas")
+	return reconcile.Result{}, nil
+}
+
+// abortCanary removes the canary Deployment and scales the stable one back.
+// The aborted spec is not retried until it changes.
+func (r *ReconcileRokku) abortCanary(ctx context.Context, rokku *rokkuv1alpha1.Rokku, stable *appv1.Deployment, reason string) (reconcile.Result, error) {
+	log.WithName("abortCanary").Info("Aborting canary rollout", "Rokku", rokku.Name, "Reason", reason)
+	if err := r.deleteCanary(ctx, rokku); err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := r.scaleDeployment(ctx, stable, *k8s.DeploymentReplicas(rokku)); err != nil {
+		return reconcile.Result{}, err
+	}
+	rokku.Status.Rollout.Phase = rokkuv1alpha1.RolloutAborted
+	rokku.Status.Rollout.Message = reason
+	rokku.Status.Rollout.StepStartTime = nil
+	setCondition(&rokku.Status, rokkuv1alpha1.RokkuProgressing, corev1.ConditionFalse, "CanaryAborted", reason)
+	return reconcile.Result{}, nil
+}
+
+// reconcileBlueGreen rolls the Rokku spec out by bringing up a full Deployment
+// of the color not selected by the Service and switching the Service to it
+// once it's available. The previous color is kept for the rollback window.
+func (r *ReconcileRokku) reconcileBlueGreen(ctx context.Context, rokku *rokkuv1alpha1.Rokku) (reconcile.Result, error) {
+	logger := log.WithName("reconcileBlueGreen").WithValues("Rokku", types.NamespacedName{Name: rokku.Name, Namespace: rokku.Namespace})
+
+	k8s.SetRokkuDefaults(rokku)
+	hash, err := k8s.SpecHash(rokku.Spec)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to hash rokku spec: %v", err)
+	}
+
+	if active := rokku.Status.ActiveColor; active != "" {
+		activeDeploy := &appv1.Deployment{}
+		err := r.client.Get(ctx, types.NamespacedName{Name: k8s.ColorName(rokku.Name, active), Namespace: rokku.Namespace}, activeDeploy)
+		if err != nil && !errors.IsNotFound(err) {
+			return reconcile.Result{}, fmt.Errorf("failed to retrieve deployment: %v", err)
+		}
+		if errors.IsNotFound(err) {
+			logger.Info("Active Deployment not found, bringing up a new one", "Color", active)
+			rokku.Status.ActiveColor = ""
+		} else {
+			activeSpec, err := k8s.ExtractRokkuSpec(activeDeploy.ObjectMeta)
+			if err != nil {
+				return reconcile.Result{}, fmt.Errorf("failed to extract rokku from deployment: %v", err)
+			}
+			activeHash, err := k8s.SpecHash(activeSpec)
+			if err != nil {
+				return reconcile.Result{}, fmt.Errorf("failed to hash rokku spec: %v", err)
+			}
+			if activeHash == hash {
+				newDeploy, err := k8s.NewColorDeployment(rokku, active)
+				if err != nil {
+					return reconcile.Result{}, fmt.Errorf("failed to assemble deployment from Rokku: %v", err)
+				}
+				if deploymentReady(activeDeploy) {
+					markRevisionComplete(rokku)
+				}
+				if err := r.updateDeployment(ctx, rokku, activeDeploy, activeSpec, newDeploy); err != nil {
+					return reconcile.Result{}, err
+				}
+				return r.expirePreviousColor(ctx, rokku)
+			}
+		}
+	}
+
+	rollout := rokku.Status.Rollout
+	if rollout != nil && rollout.SpecHash == hash && rollout.Phase == rokkuv1alpha1.RolloutAborted {
+		return reconcile.Result{}, nil
+	}
+	if rollout == nil || rollout.SpecHash != hash || rollout.Phase != rokkuv1alpha1.RolloutProgressing {
+		now := metav1.Now()
+		rollout = &rokkuv1alpha1.RolloutStatus{
+			Phase:         rokkuv1alpha1.RolloutProgressing,
+			SpecHash:      hash,
+			StepStartTime: &now,
+		}
+		rokku.Status.Rollout = rollout
+		logger.Info("Starting blue/green rollout", "SpecHash", hash)
+	}
+
+	// Until the Service is switched for the first time, it selects every Rokku
+	// pod, the preview ones included. The first preview hence runs the spec of
+	// the workload it takes over, and the new spec is only rolled out once the
+	// Service selects that color.
+	preview := k8s.OtherColor(rokku.Status.ActiveColor)
+	previewRokku := rokku
+	if rokku.Status.ActiveColor == "" {
+		seed, err := r.blueGreenSeed(ctx, rokku, hash)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if seed != nil {
+			previewRokku = seed
+		}
+	}
+	previewDeploy, err := k8s.NewColorDeployment(previewRokku, preview)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to assemble deployment from Rokku: %v", err)
+	}
+	currPreview, err := r.applyDeployment(ctx, previewDeploy)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	rollout.Message = fmt.Sprintf("waiting for the %s Deployment to become available", preview)
+	setCondition(&rokku.Status, rokkuv1alpha1.RokkuProgressing, corev1.ConditionTrue, "BlueGreenProgressing", rollout.Message)
+
+	if !deploymentReady(currPreview) {
+		deadline := defaultProgressDeadline
+		if d := rokku.Spec.Rollout.ProgressDeadlineSeconds; d != nil {
+			deadline = time.Duration(*d) * time.Second
+		}
+		if time.Since(rollout.StepStartTime.Time) <= deadline {
+			return reconcile.Result{RequeueAfter: canaryPollInterval}, nil
+		}
+		reason := fmt.Sprintf("%s pods not ready after %s", preview, deadline)
+		logger.Info("Aborting blue/green rollout", "Reason", reason)
+		if err := r.deleteIfExists(ctx, types.NamespacedName{Name: previewDeploy.Name, Namespace: rokku.Namespace}, &appv1.Deployment{}); err != nil {
+			return reconcile.Result{}, err
+		}
+		rollout.Phase = rokkuv1alpha1.RolloutAborted
+		rollout.Message = reason
+		rollout.StepStartTime = nil
+		setCondition(&rokku.Status, rokkuv1alpha1.RokkuProgressing, corev1.ConditionFalse, "BlueGreenAborted", reason)
+		return reconcile.Result{}, nil
+	}
+
+	now := metav1.Now()
+	rokku.Status.ActiveColor = preview
+	if previewRokku != rokku {
+		rollout.Message = fmt.Sprintf("Service pinned to the %s Deployment running the previous spec", preview)
+		rollout.StepStartTime = &now
+		setCondition(&rokku.Status, rokkuv1alpha1.RokkuProgressing, corev1.ConditionTrue, "BlueGreenProgressing", rollout.Message)
+		logger.Info("Pinned Service", "Color", preview)
+		return reconcile.Result{Requeue: true}, nil
+	}
+	rollout.Phase = rokkuv1alpha1.RolloutPromoted
+	rollout.Message = fmt.Sprintf("Service switched to the %s Deployment", preview)
+	rollout.StepStartTime = nil
+	rollout.CompletionTime = &now
+	markRevisionComplete(rokku)
+	setCondition(&rokku.Status, rokkuv1alpha1.RokkuProgressing, corev1.ConditionTrue, "BlueGreenSwitched", rollout.Message)
+	logger.Info("Switched Service", "Color", preview)
+
+	return reconcile.Result{RequeueAfter: rollbackWindow(rokku)}, nil
+}
+
+// blueGreenSeed returns a copy of the Rokku running the spec of the Deployment
+// or the DaemonSet the BlueGreen strategy takes over, if any and when it
+// differs from the spec with the given hash.
+func (r *ReconcileRokku) blueGreenSeed(ctx context.Context, rokku *rokkuv1alpha1.Rokku, hash string) (*rokkuv1alpha1.Rokku, error) {
+	name := types.NamespacedName{Name: rokku.Name, Namespace: rokku.Namespace}
+	var meta *metav1.ObjectMeta
+	deploy := &appv1.Deployment{}
+	daemonSet := &appv1.DaemonSet{}
+	if err := r.client.Get(ctx, name, deploy); err == nil {
+		meta = &deploy.ObjectMeta
+	} else if !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to retrieve deployment: %v", err)
+	} else if err := r.client.Get(ctx, name, daemonSet); err == nil {
+		meta = &daemonSet.ObjectMeta
+	} else if !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to retrieve daemonset: %v", err)
+	}
+	if meta == nil {
+		return nil, nil
+	}
+
+	spec, err := k8s.ExtractRokkuSpec(*meta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract rokku from %s: %v", rokku.Name, err)
+	}
+	seedHash, err := k8s.SpecHash(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash rokku spec: %v", err)
+	}
+	if seedHash == hash {
+		return nil, nil
+	}
+
+	seed := rokku.DeepCopy()
+	spec.Replicas = rokku.Spec.Replicas
+	spec.Maintenance = rokku.Spec.Maintenance
+	seed.Spec = spec
+	return seed, nil
+}
+
+// loadActiveColor reads the active color back from the Rokku Service, which
+// records it along with its selector. The status may be stale when its last
+// update failed.
+func (r *ReconcileRokku) loadActiveColor(ctx context.Context, rokku *rokkuv1alpha1.Rokku) error {
+	var svc corev1.Service
+	err := r.client.Get(ctx, types.NamespacedName{Name: k8s.ServiceName(rokku.Name), Namespace: rokku.Namespace}, &svc)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to retrieve Service resource: %v", err)
+	}
+	if color := k8s.GetActiveColorFromObject(&svc); color != "" {
+		rokku.Status.ActiveColor = color
+	}
+	return nil
+}
+
+// expirePreviousColor removes the Deployment of the previous color, along with
+// the ones created before the BlueGreen strategy was enabled, once the
+// rollback window is over.
+func (r *ReconcileRokku) expirePreviousColor(ctx context.Context, rokku *rokkuv1alpha1.Rokku) (reconcile.Result, error) {
+	if rollout := rokku.Status.Rollout; rollout != nil && rollout.CompletionTime != nil {
+		if remaining := rollbackWindow(rokku) - time.Since(rollout.CompletionTime.Time); remaining > 0 {
+			return reconcile.Result{RequeueAfter: remaining}, nil
+		}
+	}
+
+	previous := k8s.OtherColor(rokku.Status.ActiveColor)
+	for _, name := range []string{k8s.ColorName(rokku.Name, previous), rokku.Name, k8s.CanaryName(rokku.Name)} {
+		if err := r.deleteIfExists(ctx, types.NamespacedName{Name: name, Namespace: rokku.Namespace}, &appv1.Deployment{}); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	return reconcile.Result{}, nil
+}
+
+// cleanupBlueGreen removes the colored Deployments left by the BlueGreen
+// strategy once the Deployment replacing them is ready.
+func (r *ReconcileRokku) cleanupBlueGreen(ctx context.Context, rokku *rokkuv1alpha1.Rokku, deploy *appv1.Deployment) error {
+	if rokku.Status.ActiveColor == "" || !deploymentReady(deploy) {
+		return nil
+	}
+	for _, color := range []string{k8s.ColorBlue, k8s.ColorGreen} {
+		if err := r.deleteIfExists(ctx, types.NamespacedName{Name: k8s.ColorName(rokku.Name, color), Namespace: rokku.Namespace}, &appv1.Deployment{}); err != nil {
+			return err
+		}
+	}
+	rokku.Status.ActiveColor = ""
+	return nil
+}
+
+func rollbackWindow(rokku *rokkuv1alpha1.Rokku) time.Duration {
+	if bg := rokku.Spec.Rollout.BlueGreen; bg != nil && bg.RollbackWindowSeconds != nil {
+		return time.Duration(*bg.RollbackWindowSeconds) * time.Second
+	}
+	return defaultRollbackWindow
+}
+
+// checkRollingUpdate records the spec of a fully rolled out Deployment as the
+// last good one, and rolls the Deployment back to it when the rollout exceeds
+// its progress deadline and spec.rollout.autoRollback is set.
+func (r *ReconcileRokku) checkRollingUpdate(ctx context.Context, rokku *rokkuv1alpha1.Rokku, deploy *appv1.Deployment) error {
+	if deploymentReady(deploy) {
+		markRevisionComplete(rokku)
+		setCondition(&rokku.Status, rokkuv1alpha1.RokkuDegraded, corev1.ConditionFalse, "RolloutComplete", "")
+		if !k8s.SetLastGoodRokkuSpec(&deploy.ObjectMeta) {
+			return nil
+		}
+		if err := r.client.Update(ctx, deploy); err != nil {
+			return fmt.Errorf("failed to record last good spec in deployment: %v", err)
+		}
+		return nil
+	}
+
+	if !progressDeadlineExceeded(deploy) {
+		return nil
+	}
+
+	degraded := isConditionTrue(rokku.Status, rokkuv1alpha1.RokkuDegraded)
+	lastGood, err := k8s.ExtractLastGoodRokkuSpec(deploy.ObjectMeta)
+	if err != nil {
+		return err
+	}
+
+	if rokku.Spec.Rollout == nil || !rokku.Spec.Rollout.AutoRollback || lastGood == nil || reflect.DeepEqual(*lastGood, rokku.Spec) {
+		message := "deployment exceeded its progress deadline"
+		setCondition(&rokku.Status, rokkuv1alpha1.RokkuDegraded, corev1.ConditionTrue, "ProgressDeadlineExceeded", message)
+		if !degraded {
+			r.recorder.Event(rokku, corev1.EventTypeWarning, "ProgressDeadlineExceeded", message)
+		}
+		return nil
+	}
+
+	hash, err := k8s.SpecHash(rokku.Spec)
+	if err != nil {
+		return fmt.Errorf("failed to hash rokku spec: %v", err)
+	}
+	goodHash, err := k8s.SpecHash(*lastGood)
+	if err != nil {
+		return fmt.Errorf("failed to hash rokku spec: %v", err)
+	}
+
+	good := rokku.DeepCopy()
+	good.Spec = *lastGood
+	goodDeploy, err := k8s.NewDeployment(good)
+	if err != nil {
+		return fmt.Errorf("failed to assemble deployment from last good spec: %v", err)
+	}
+	deploy.Spec = goodDeploy.Spec
+	if err := k8s.SetRokkuSpec(&deploy.ObjectMeta, *lastGood); err != nil {
+		return fmt.Errorf("failed to set rokku spec into object meta: %v", err)
+	}
+	if err := r.client.Update(ctx, deploy); err != nil {
+		return fmt.Errorf("failed to roll deployment back: %v", err)
+	}
+
+	message := fmt.Sprintf("deployment exceeded its progress deadline, rolled back from spec %s to the last good spec %s", hash, goodHash)
+	rokku.Status.Rollout = &rokkuv1alpha1.RolloutStatus{
+		Phase:    rokkuv1alpha1.RolloutAborted,
+		SpecHash: hash,
+		Message:  message,
+	}
+	setCondition(&rokku.Status, rokkuv1alpha1.RokkuDegraded, corev1.ConditionTrue, "RolledBack", message)
+	r.recorder.Event(rokku, corev1.EventTypeWarning, "RolledBack", message)
+	log.WithName("checkRollingUpdate").Info("Rolled deployment back", "Rokku", rokku.Name, "From", hash, "To", goodHash)
+	return nil
+}
+
+// isRolledBack tells whether the current Rokku spec was rolled back and must
+// not be applied again until it changes.
+func isRolledBack(rokku *rokkuv1alpha1.Rokku) (bool, error) {
+	rollout := rokku.Status.Rollout
+	if rollout == nil || rollout.Phase != rokkuv1alpha1.RolloutAborted {
+		return false, nil
+	}
+	hash, err := k8s.SpecHash(rokku.Spec)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash rokku spec: %v", err)
+	}
+	return rollout.SpecHash == hash, nil
+}
+
+// progressDeadlineExceeded tells whether the latest generation of the given
+// Deployment failed to progress.
+func progressDeadlineExceeded(deploy *appv1.Deployment) bool {
+	if deploy.Status.ObservedGeneration < deploy.Generation {
+		return false
+	}
+	for _, cond := range deploy.Status.Conditions {
+		if cond.Type == appv1.DeploymentProgressing {
+			return cond.Status == corev1.ConditionFalse && cond.Reason == "ProgressDeadlineExceeded"
+		}
+	}
+	return false
+}
+
+func (r *ReconcileRokku) deleteCanary(ctx context.Context, rokku *rokkuv1alpha1.Rokku) error {
+	name := types.NamespacedName{Name: k8s.CanaryName(rokku.Name), Namespace: rokku.Namespace}
+	return r.deleteIfExists(ctx, name, &appv1.Deployment{})
+}
+
+// applyDeployment creates the given Deployment or updates the existing one
+// when it was generated from another spec or has another number of replicas.
+// It returns the Deployment as found in the cluster.
+func (r *ReconcileRokku) applyDeployment(ctx context.Context, deploy *appv1.Deployment) (*appv1.Deployment, error) {
+	curr := &appv1.Deployment{}
+	err := r.client.Get(ctx, types.NamespacedName{Name: deploy.Name, Namespace: deploy.Namespace}, curr)
+	if err != nil && errors.IsNotFound(err) {
+		if err := r.client.Create(ctx, deploy); err != nil {
+			return nil, fmt.Errorf("failed to create deployment %s: %v", deploy.Name, err)
+		}
+		return deploy, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve deployment %s: %v", deploy.Name, err)
+	}
+
+	currSpec, err := k8s.ExtractRokkuSpec(curr.ObjectMeta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract rokku from deployment %s: %v", deploy.Name, err)
+	}
+	newSpec, err := k8s.ExtractRokkuSpec(deploy.ObjectMeta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract rokku from deployment %s: %v", deploy.Name, err)
+	}
+	if reflect.DeepEqual(currSpec, newSpec) && reflect.DeepEqual(curr.Spec.Replicas, deploy.Spec.Replicas) {
+		return curr, nil
+	}
+
+	curr.Spec = deploy.Spec
+	curr.Annotations = deploy.Annotations
+	if err := r.client.Update(ctx, curr); err != nil {
+		return nil, fmt.Errorf("failed to update deployment %s: %v", deploy.Name, err)
+	}
+	return curr, nil
+}
+
+func (r *ReconcileRokku) scaleDeployment(ctx context.Context, deploy *appv1.Deployment, replicas int32) error {
+	if deploy.Spec.Replicas != nil && *deploy.Spec.Replicas == replicas {
+		return nil
+	}
+	deploy.Spec.Replicas = &replicas
+	if err := r.client.Update(ctx, deploy); err != nil {
+		return fmt.Errorf("failed to scale deployment %s: %v", deploy.Name, err)
+	}
+	return nil
+}
+
+// deploymentReady tells whether every replica of the latest generation of the
+// given Deployment is ready.
+func deploymentReady(deploy *appv1.Deployment) bool {
+	replicas := int32(1)
+	if deploy.Spec.Replicas != nil {
+		replicas = *deploy.Spec.Replicas
+	}
+	return deploy.Generation > 0 &&
+		deploy.Status.ObservedGeneration >= deploy.Generation &&
+		deploy.Status.UpdatedReplicas >= replicas &&
+		deploy.Status.ReadyReplicas >= replicas
+}
+
+var prometheusClient = &http.Client{Timeout: 10 * time.Second}
+
+// queryPrometheus runs an instant query returning a single value. Queries
+// without samples, such as ratios without traffic, return 0.
+func queryPrometheus(ctx context.Context, prometheusURL, query string) (float64, error) {
+	u := fmt.Sprintf("%s/api/v1/query?query=%s", prometheusURL, url.QueryEscape(query))
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := prometheusClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %d from prometheus", resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Result []struct {
+				Value []interface{} `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("failed to decode prometheus response: %v", err)
+	}
+	if len(body.Data.Result) == 0 || len(body.Data.Result[0].Value) != 2 {
+		return 0, nil
+	}
+	raw, ok := body.Data.Result[0].Value[1].(string)
+	if !ok {
+		return 0, fmt.Errorf("unexpected value %v from prometheus", body.Data.Result[0].Value[1])
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(value) {
+		return 0, nil
+	}
+	return value, nil
+}
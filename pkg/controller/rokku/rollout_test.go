@@ -0,0 +1,159 @@
+package rokku
+
+import (
+	"context"
+	"testing"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"github.com/jwi078/rokku-operator/pkg/k8s"
+	appv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileCanaryReplicas(t *testing.T) {
+	two := int32(2)
+	tests := []struct {
+		name        string
+		maintenance *rokkuv1alpha1.RokkuMaintenance
+		wantStable  int32
+		wantCanary  int32
+	}{
+		{name: "first step", wantStable: 3, wantCanary: 1},
+		{name: "maintenance", maintenance: &rokkuv1alpha1.RokkuMaintenance{Enabled: true}, wantStable: 0, wantCanary: 0},
+		{name: "maintenance replicas", maintenance: &rokkuv1alpha1.RokkuMaintenance{Enabled: true, Replicas: &two}, wantStable: 1, wantCanary: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			four := int32(4)
+			rokku := newTestRokku()
+			rokku.Spec.Image = "wbaa/rokku:2"
+			rokku.Spec.Replicas = &four
+			rokku.Spec.Maintenance = tt.maintenance
+			rokku.Spec.Rollout = &rokkuv1alpha1.RokkuRollout{Strategy: rokkuv1alpha1.RolloutStrategyCanary}
+			k8s.SetRokkuDefaults(rokku)
+
+			stableRokku := rokku.DeepCopy()
+			stableRokku.Spec.Image = "wbaa/rokku:1"
+			stable, err := k8s.NewDeployment(stableRokku)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			newDeploy, err := k8s.NewDeployment(rokku)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			r := newTestReconciler(stable)
+			currStable := &appv1.Deployment{}
+			if err := r.client.Get(ctx, types.NamespacedName{Name: "rokku", Namespace: "default"}, currStable); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := r.reconcileCanary(ctx, rokku, currStable, stableRokku.Spec, newDeploy); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for name, want := range map[string]int32{"rokku": tt.wantStable, "rokku-canary": tt.wantCanary} {
+				deploy := &appv1.Deployment{}
+				if err := r.client.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, deploy); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if *deploy.Spec.Replicas != want {
+					t.Errorf("%s replicas = %d, want %d", name, *deploy.Spec.Replicas, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileBlueGreenPinsServiceFirst(t *testing.T) {
+	ctx := context.Background()
+	previous := newTestRokku()
+	previous.Spec.Image = "wbaa/rokku:1"
+	original, err := k8s.NewDeployment(previous)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r := newTestReconciler(original)
+
+	rokku := newTestRokku()
+	rokku.Spec.Image = "wbaa/rokku:2"
+	rokku.Spec.Rollout = &rokkuv1alpha1.RokkuRollout{Strategy: rokkuv1alpha1.RolloutStrategyBlueGreen}
+
+	image := func(name string) string {
+		deploy := &appv1.Deployment{}
+		err := r.client.Get(ctx, types.NamespacedName{Name: name, Namespace: "default"}, deploy)
+		if errors.IsNotFound(err) {
+			return ""
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return deploy.Spec.Template.Spec.Containers[0].Image
+	}
+	reconcileBlueGreen := func() reconcile.Result {
+		result, err := r.reconcileBlueGreen(ctx, rokku)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return result
+	}
+
+	// The first preview runs the previous spec, as the Service selects it
+	// along with the original pods
+	reconcileBlueGreen()
+	if got := image("rokku-blue"); got != "wbaa/rokku:1" {
+		t.Fatalf("rokku-blue image = %q, want the previous one", got)
+	}
+	if got := image("rokku-green"); got != "" {
+		t.Fatalf("rokku-green must not exist before the Service is pinned, got image %q", got)
+	}
+
+	setDeploymentReady(t, r, "rokku-blue")
+	if result := reconcileBlueGreen(); !result.Requeue {
+		t.Errorf("expected a requeue once the Service is pinned")
+	}
+	if rokku.Status.ActiveColor != k8s.ColorBlue {
+		t.Fatalf("active color = %q, want blue", rokku.Status.ActiveColor)
+	}
+	svc, err := k8s.NewService(rokku)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if svc.Spec.Selector["rokku.ing.com/color"] != k8s.ColorBlue {
+		t.Errorf("service selector = %v, want the blue pods", svc.Spec.Selector)
+	}
+
+	// The new spec is only rolled out once the Service selects blue
+	reconcileBlueGreen()
+	if got := image("rokku-green"); got != "wbaa/rokku:2" {
+		t.Errorf("rokku-green image = %q, want the new one", got)
+	}
+	if rokku.Status.ActiveColor != k8s.ColorBlue {
+		t.Errorf("active color = %q, want blue until green is ready", rokku.Status.ActiveColor)
+	}
+
+	setDeploymentReady(t, r, "rokku-green")
+	reconcileBlueGreen()
+	if rokku.Status.ActiveColor != k8s.ColorGreen {
+		t.Errorf("active color = %q, want green", rokku.Status.ActiveColor)
+	}
+}
+
+// setDeploymentReady marks every replica of the given Deployment as ready.
+func setDeploymentReady(t *testing.T, r *ReconcileRokku, name string) {
+	deploy := &appv1.Deployment{}
+	if err := r.client.Get(context.Background(), types.NamespacedName{Name: name, Namespace: "default"}, deploy); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	deploy.Generation = 1
+	deploy.Status.ObservedGeneration = 1
+	deploy.Status.UpdatedReplicas = *deploy.Spec.Replicas
+	deploy.Status.ReadyReplicas = *deploy.Spec.Replicas
+	if err := r.client.Update(context.Background(), deploy); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
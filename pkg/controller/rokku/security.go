@@ -0,0 +1,25 @@
+package rokku
+
+import (
+	"fmt"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"github.com/jwi078/rokku-operator/pkg/k8s"
+)
+
+// refreshSecurityViolations reports the violations of the restricted Pod
+// Security Standard left in the pod template of a Restricted Rokku, e.g. by
+// sidecars or overrides.
+func refreshSecurityViolations(rokku *rokkuv1alpha1.Rokku) error {
+	rokku.Status.SecurityViolations = nil
+	if rokku.Spec.SecurityProfile != rokkuv1alpha1.SecurityProfileRestricted {
+		return nil
+	}
+
+	deploy, err := k8s.NewDeployment(rokku.DeepCopy())
+	if err != nil {
+		return fmt.Errorf("failed to assemble deployment from Rokku: %v", err)
+	}
+	rokku.Status.SecurityViolations = k8s.RestrictedViolations(&deploy.Spec.Template)
+	return nil
+}
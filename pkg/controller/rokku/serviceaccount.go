@@ -0,0 +1,185 @@
+package rokku
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"github.com/jwi078/rokku-operator/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// reconcileServiceAccount creates the ServiceAccount the Rokku pods run as,
+// unless the pod template names an existing one, along with the Role and
+// RoleBinding granting its permissions. Objects of the same name not
+// controlled by the Rokku are left alone, the conflict is reported in the
+// ServiceAccountReady condition instead.
+func (r *ReconcileRokku) reconcileServiceAccount(ctx context.Context, rokku *rokkuv1alpha1.Rokku) error {
+	var conflicts []string
+	for _, reconcile := range []func(context.Context, *rokkuv1alpha1.Rokku) (string, error){
+		r.applyServiceAccount,
+		r.applyRole,
+		r.applyRoleBinding,
+	} {
+		conflict, err := reconcile(ctx, rokku)
+		if err != nil {
+			return err
+		}
+		if conflict != "" {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+
+	if !k8s.IsServiceAccountManaged(rokku) && !k8s.HasRoleBinding(rokku) {
+		removeCondition(&rokku.Status, rokkuv1alpha1.RokkuServiceAccountReady)
+		return nil
+	}
+	if len(conflicts) == 0 {
+		setCondition(&rokku.Status, rokkuv1alpha1.RokkuServiceAccountReady, corev1.ConditionTrue, "ServiceAccountReady", "")
+		return nil
+	}
+	message := strings.Join(conflicts, "; ")
+	if cond := getCondition(rokku.Status, rokkuv1alpha1.RokkuServiceAccountReady); cond == nil || cond.Status != corev1.ConditionFalse || cond.Message != message {
+		r.recorder.Event(rokku, corev1.EventTypeWarning, "NameConflict", message)
+	}
+	setCondition(&rokku.Status, rokkuv1alpha1.RokkuServiceAccountReady, corev1.ConditionFalse, "NameConflict", message)
+	return nil
+}
+
+// applyServiceAccount creates or updates the ServiceAccount of the Rokku
+// pods. It returns the conflict found, if any.
+func (r *ReconcileRokku) applyServiceAccount(ctx context.Context, rokku *rokkuv1alpha1.Rokku) (string, error) {
+	if !k8s.IsServiceAccountManaged(rokku) {
+		return "", nil
+	}
+
+	newSA := k8s.NewServiceAccount(rokku)
+	logger := log.WithName("reconcileServiceAccount").WithValues("ServiceAccount", newSA.Name)
+
+	var currentSA corev1.ServiceAccount
+	err := r.client.Get(ctx, types.NamespacedName{Name: newSA.Name, Namespace: newSA.Namespace}, &currentSA)
+	if err != nil && errors.IsNotFound(err) {
+		logger.V(4).Info("Creating a ServiceAccount resource")
+		return "", r.client.Create(ctx, newSA)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve ServiceAccount resource: %v", err)
+	}
+	if !metav1.IsControlledBy(&currentSA, rokku) {
+		return fmt.Sprintf("ServiceAccount %q is not controlled by the Rokku", newSA.Name), nil
+	}
+
+	if reflect.DeepEqual(newSA.Annotations, currentSA.Annotations) &&
+		reflect.DeepEqual(newSA.Labels, currentSA.Labels) &&
+		reflect.DeepEqual(newSA.AutomountServiceAccountToken, currentSA.AutomountServiceAccountToken) {
+		return "", nil
+	}
+
+	// The token secrets are filled in by the token controller, so only the
+	// fields owned by the operator are updated
+	currentSA.Annotations = newSA.Annotations
+	currentSA.Labels = newSA.Labels
+	currentSA.AutomountServiceAccountToken = newSA.AutomountServiceAccountToken
+	logger.V(4).Info("Updating ServiceAccount resource")
+	return "", r.client.Update(ctx, &currentSA)
+}
+
+// applyRole creates or updates the Role holding the permissions listed by
+// spec.serviceAccount.rules. It returns the conflict found, if any.
+func (r *ReconcileRokku) applyRole(ctx context.Context, rokku *rokkuv1alpha1.Rokku) (string, error) {
+	name := types.NamespacedName{Name: k8s.RoleName(rokku.Name), Namespace: rokku.Namespace}
+
+	if !k8s.HasRole(rokku) {
+		return "", r.deleteIfControlled(ctx, rokku, name, &rbacv1.Role{})
+	}
+
+	logger := log.WithName("reconcileRole").WithValues("Role", name)
+
+	newRole := k8s.NewRole(rokku)
+	var currentRole rbacv1.Role
+	err := r.client.Get(ctx, name, &currentRole)
+	switch {
+	case errors.IsNotFound(err):
+		logger.V(4).Info("Creating a Role resource")
+		return "", r.client.Create(ctx, newRole)
+	case err != nil:
+		return "", fmt.Errorf("failed to retrieve Role resource: %v", err)
+	case !metav1.IsControlledBy(&currentRole, rokku):
+		return fmt.Sprintf("Role %q is not controlled by the Rokku", name.Name), nil
+	case !reflect.DeepEqual(newRole.Rules, currentRole.Rules) || !reflect.DeepEqual(newRole.Labels, currentRole.Labels):
+		newRole.ResourceVersion = currentRole.ResourceVersion
+		logger.V(4).Info("Updating Role resource")
+		return "", r.client.Update(ctx, newRole)
+	}
+	return "", nil
+}
+
+// applyRoleBinding binds the Role created from spec.serviceAccount.rules, or
+// else the role referenced by spec.serviceAccount.roleRef, to the Rokku
+// ServiceAccount. It returns the conflict found, if any.
+func (r *ReconcileRokku) applyRoleBinding(ctx context.Context, rokku *rokkuv1alpha1.Rokku) (string, error) {
+	name := types.NamespacedName{Name: k8s.RoleBindingName(rokku.Name), Namespace: rokku.Namespace}
+
+	if !k8s.HasRoleBinding(rokku) {
+		return "", r.deleteIfControlled(ctx, rokku, name, &rbacv1.RoleBinding{})
+	}
+
+	logger := log.WithName("reconcileRoleBinding").WithValues("RoleBinding", name)
+
+	newBinding := k8s.NewRoleBinding(rokku)
+	var currentBinding rbacv1.RoleBinding
+	err := r.client.Get(ctx, name, &currentBinding)
+	switch {
+	case errors.IsNotFound(err):
+		logger.V(4).Info("Creating a RoleBinding resource")
+		return "", r.client.Create(ctx, newBinding)
+	case err != nil:
+		return "", fmt.Errorf("failed to retrieve RoleBinding resource: %v", err)
+	case !metav1.IsControlledBy(&currentBinding, rokku):
+		return fmt.Sprintf("RoleBinding %q is not controlled by the Rokku", name.Name), nil
+	case newBinding.RoleRef != currentBinding.RoleRef:
+		// The role reference of a RoleBinding can't be changed, the binding
+		// is created again
+		logger.V(4).Info("Replacing RoleBinding resource")
+		if err := r.client.Delete(ctx, &currentBinding); err != nil && !errors.IsNotFound(err) {
+			return "", fmt.Errorf("failed to delete RoleBinding resource: %v", err)
+		}
+		return "", r.client.Create(ctx, newBinding)
+	case !reflect.DeepEqual(newBinding.Subjects, currentBinding.Subjects):
+		newBinding.ResourceVersion = currentBinding.ResourceVersion
+		logger.V(4).Info("Updating RoleBinding resource")
+		return "", r.client.Update(ctx, newBinding)
+	}
+	return "", nil
+}
+
+// deleteIfControlled deletes the given object unless it's missing or not
+// controlled by the Rokku.
+func (r *ReconcileRokku) deleteIfControlled(ctx context.Context, rokku *rokkuv1alpha1.Rokku, name types.NamespacedName, obj runtime.Object) error {
+	err := r.client.Get(ctx, name, obj)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to retrieve %s: %v", name, err)
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(accessor, rokku) {
+		return nil
+	}
+	if err := r.client.Delete(ctx, obj); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete %s: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package rokku
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"github.com/jwi078/rokku-operator/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestReconcileServiceAccount(t *testing.T) {
+	ctx := context.Background()
+	rokku := newTestRokku()
+	annotations := map[string]string{"iam.gke.io/gcp-service-account": "rokku@example.iam.gserviceaccount.com"}
+	rokku.Spec.ServiceAccount = &rokkuv1alpha1.RokkuServiceAccount{
+		Annotations: annotations,
+		Rules: []rbacv1.PolicyRule{
+			{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}},
+		},
+	}
+	r := newTestReconciler()
+	name := types.NamespacedName{Name: "rokku-rokku", Namespace: "default"}
+
+	if err := r.reconcileServiceAccount(ctx, rokku); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var sa corev1.ServiceAccount
+	if err := r.client.Get(ctx, name, &sa); err != nil {
+		t.Fatalf("expected the ServiceAccount: %v", err)
+	}
+	if sa.Annotations["iam.gke.io/gcp-service-account"] == "" || sa.AutomountServiceAccountToken == nil || *sa.AutomountServiceAccountToken {
+		t.Errorf("ServiceAccount = %+v, want the annotations and no token mounted", sa)
+	}
+	var role rbacv1.Role
+	if err := r.client.Get(ctx, name, &role); err != nil {
+		t.Fatalf("expected the Role: %v", err)
+	}
+	var binding rbacv1.RoleBinding
+	if err := r.client.Get(ctx, name, &binding); err != nil {
+		t.Fatalf("expected the RoleBinding: %v", err)
+	}
+	if binding.RoleRef.Kind != "Role" || binding.RoleRef.Name != "rokku-rokku" || binding.Subjects[0].Name != "rokku-rokku" {
+		t.Errorf("RoleBinding = %+v, want the Role bound to the ServiceAccount", binding)
+	}
+	if cond := getCondition(rokku.Status, rokkuv1alpha1.RokkuServiceAccountReady); cond == nil || cond.Status != corev1.ConditionTrue {
+		t.Errorf("service account condition = %+v, want true", cond)
+	}
+
+	// Binding an existing role instead removes the generated one
+	rokku.Spec.ServiceAccount.Rules = nil
+	rokku.Spec.ServiceAccount.RoleRef = &rokkuv1alpha1.RokkuRoleRef{Kind: "ClusterRole", Name: "view"}
+	if err := r.reconcileServiceAccount(ctx, rokku); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.client.Get(ctx, name, &rbacv1.Role{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the Role to be removed, got %v", err)
+	}
+	if err := r.client.Get(ctx, name, &binding); err != nil {
+		t.Fatalf("expected the RoleBinding: %v", err)
+	}
+	if binding.RoleRef.Kind != "ClusterRole" || binding.RoleRef.Name != "view" {
+		t.Errorf("RoleBinding roleRef = %+v, want the view ClusterRole", binding.RoleRef)
+	}
+}
+
+func TestReconcileServiceAccountConflict(t *testing.T) {
+	ctx := context.Background()
+	rokku := newTestRokku()
+	automount := true
+	foreignSA := &corev1.ServiceAccount{
+		ObjectMeta:                   metav1.ObjectMeta{Name: "rokku-rokku", Namespace: "default"},
+		AutomountServiceAccountToken: &automount,
+	}
+	foreignBinding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: "rokku-rokku", Namespace: "default"},
+		RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "admin"},
+	}
+	r := newTestReconciler(foreignSA, foreignBinding)
+	name := types.NamespacedName{Name: "rokku-rokku", Namespace: "default"}
+
+	if err := r.reconcileServiceAccount(ctx, rokku); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var sa corev1.ServiceAccount
+	if err := r.client.Get(ctx, name, &sa); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !*sa.AutomountServiceAccountToken || len(sa.OwnerReferences) != 0 {
+		t.Errorf("ServiceAccount = %+v, want it left alone", sa)
+	}
+	cond := getCondition(rokku.Status, rokkuv1alpha1.RokkuServiceAccountReady)
+	if cond == nil || cond.Status != corev1.ConditionFalse || cond.Reason != "NameConflict" {
+		t.Errorf("service account condition = %+v, want a name conflict", cond)
+	}
+	if event := nextEvent(r); !strings.Contains(event, "NameConflict") {
+		t.Errorf("event = %q, want NameConflict", event)
+	}
+
+	// The RoleBinding isn't removed either, as the Rokku doesn't control it
+	if err := r.client.Get(ctx, name, &rbacv1.RoleBinding{}); err != nil {
+		t.Errorf("expected the RoleBinding to be left alone: %v", err)
+	}
+
+	// The conflict is only reported once
+	if err := r.reconcileServiceAccount(ctx, rokku); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event := nextEvent(r); event != "" {
+		t.Errorf("unexpected event %q", event)
+	}
+}
+
+func TestReconcileServiceAccountUnmanaged(t *testing.T) {
+	ctx := context.Background()
+	rokku := newTestRokku()
+	rokku.Spec.PodTemplate.ServiceAccountName = "existing"
+	setCondition(&rokku.Status, rokkuv1alpha1.RokkuServiceAccountReady, corev1.ConditionTrue, "ServiceAccountReady", "")
+	r := newTestReconciler()
+
+	if err := r.reconcileServiceAccount(ctx, rokku); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.client.Get(ctx, types.NamespacedName{Name: "existing", Namespace: "default"}, &corev1.ServiceAccount{}); !errors.IsNotFound(err) {
+		t.Errorf("no ServiceAccount must be created for an existing one, got %v", err)
+	}
+	if k8s.ServiceAccountName(rokku) != "existing" {
+		t.Errorf("service account name = %q, want existing", k8s.ServiceAccountName(rokku))
+	}
+	if cond := getCondition(rokku.Status, rokkuv1alpha1.RokkuServiceAccountReady); cond != nil {
+		t.Errorf("service account condition = %+v, want none", cond)
+	}
+}
@@ -0,0 +1,105 @@
+package rokku
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"github.com/jwi078/rokku-operator/pkg/k8s"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// reconcileSTS runs the managed rokku-sts and tells whether it's ready, or
+// removes it once it's no longer managed.
+func (r *ReconcileRokku) reconcileSTS(ctx context.Context, rokku *rokkuv1alpha1.Rokku) (bool, error) {
+	name := types.NamespacedName{
+		Name:      k8s.STSName(rokku.Name),
+		Namespace: rokku.Namespace,
+	}
+
+	if !k8s.IsSTSManaged(rokku) {
+		removeCondition(&rokku.Status, rokkuv1alpha1.RokkuSTSReady)
+		if err := r.deleteIfExists(ctx, name, &appv1.Deployment{}); err != nil {
+			return false, err
+		}
+		return true, r.deleteIfExists(ctx, name, &corev1.Service{})
+	}
+
+	newService := k8s.NewSTSService(rokku)
+	var currService corev1.Service
+	err := r.client.Get(ctx, name, &currService)
+	switch {
+	case errors.IsNotFound(err):
+		if err := r.client.Create(ctx, newService); err != nil {
+			return false, fmt.Errorf("failed to create sts service: %v", err)
+		}
+	case err != nil:
+		return false, fmt.Errorf("failed to retrieve sts service: %v", err)
+	case !reflect.DeepEqual(currService.Spec.Ports, newService.Spec.Ports):
+		currService.Spec.Ports = newService.Spec.Ports
+		if err := r.client.Update(ctx, &currService); err != nil {
+			return false, fmt.Errorf("failed to update sts service: %v", err)
+		}
+	}
+
+	newDeploy, err := k8s.NewSTSDeployment(rokku)
+	if err != nil {
+		return false, fmt.Errorf("failed to assemble sts deployment from Rokku: %v", err)
+	}
+	var currDeploy appv1.Deployment
+	err = r.client.Get(ctx, name, &currDeploy)
+	switch {
+	case errors.IsNotFound(err):
+		if err := r.client.Create(ctx, newDeploy); err != nil {
+			return false, fmt.Errorf("failed to create sts deployment: %v", err)
+		}
+		setCondition(&rokku.Status, rokkuv1alpha1.RokkuSTSReady, corev1.ConditionFalse, "Deploying", "rokku-sts is being deployed")
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("failed to retrieve sts deployment: %v", err)
+	case k8s.GetConfigChecksumFromObject(&currDeploy) != k8s.GetConfigChecksumFromObject(newDeploy):
+		newDeploy.ResourceVersion = currDeploy.ResourceVersion
+		if err := r.client.Update(ctx, newDeploy); err != nil {
+			return false, fmt.Errorf("failed to update sts deployment: %v", err)
+		}
+		setCondition(&rokku.Status, rokkuv1alpha1.RokkuSTSReady, corev1.ConditionFalse, "Deploying", "rokku-sts is being updated")
+		return false, nil
+	}
+
+	if !deploymentReady(&currDeploy) {
+		setCondition(&rokku.Status, rokkuv1alpha1.RokkuSTSReady, corev1.ConditionFalse, "Deploying",
+			fmt.Sprintf("%d of %d rokku-sts pods are ready", currDeploy.Status.ReadyReplicas, *currDeploy.Spec.Replicas))
+		return false, nil
+	}
+	setCondition(&rokku.Status, rokkuv1alpha1.RokkuSTSReady, corev1.ConditionTrue, "Ready", "")
+	return true, nil
+}
+
+// workloadExists tells whether a workload running the Rokku pods exists,
+// whatever the workload kind and the rollout strategy.
+func (r *ReconcileRokku) workloadExists(ctx context.Context, rokku *rokkuv1alpha1.Rokku) (bool, error) {
+	workloads := []struct {
+		name string
+		obj  runtime.Object
+	}{
+		{rokku.Name, &appv1.Deployment{}},
+		{rokku.Name, &appv1.DaemonSet{}},
+		{k8s.ColorName(rokku.Name, k8s.ColorBlue), &appv1.Deployment{}},
+		{k8s.ColorName(rokku.Name, k8s.ColorGreen), &appv1.Deployment{}},
+	}
+	for _, w := range workloads {
+		err := r.client.Get(ctx, types.NamespacedName{Name: w.name, Namespace: rokku.Namespace}, w.obj)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.IsNotFound(err) {
+			return false, fmt.Errorf("failed to retrieve workload %q: %v", w.name, err)
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,90 @@
+package rokku
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"github.com/jwi078/rokku-operator/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// refreshTrustChecksum records the checksum of the CA bundles content into
+// the Rokku spec, so that the pods are replaced whenever a bundle changes. A
+// missing bundle is only reported, the checksum is recorded once it's created.
+func (r *ReconcileRokku) refreshTrustChecksum(ctx context.Context, rokku *rokkuv1alpha1.Rokku) error {
+	if rokku.Spec.Trust == nil || len(rokku.Spec.Trust.CABundles) == 0 {
+		removeCondition(&rokku.Status, rokkuv1alpha1.RokkuCABundlesAvailable)
+		return nil
+	}
+
+	bundles, missing, err := r.caBundles(ctx, rokku)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		setCondition(&rokku.Status, rokkuv1alpha1.RokkuCABundlesAvailable, corev1.ConditionFalse, "CABundleNotFound",
+			fmt.Sprintf("CA bundles not found: %s", strings.Join(missing, ", ")))
+		return nil
+	}
+	setCondition(&rokku.Status, rokkuv1alpha1.RokkuCABundlesAvailable, corev1.ConditionTrue, "CABundlesFound", "")
+
+	hash := sha256.New()
+	for _, data := range bundles {
+		hash.Write(data)
+	}
+
+	k8s.SetTrustChecksum(rokku, fmt.Sprintf("%x", hash.Sum(nil)))
+	return nil
+}
+
+// caBundles returns the content of the CA bundles, skipping the optional
+// ones that don't exist. The missing bundles which aren't optional are
+// returned apart.
+func (r *ReconcileRokku) caBundles(ctx context.Context, rokku *rokkuv1alpha1.Rokku) ([][]byte, []string, error) {
+	if rokku.Spec.Trust == nil {
+		return nil, nil, nil
+	}
+
+	var bundles [][]byte
+	var missing []string
+	for _, bundle := range rokku.Spec.Trust.CABundles {
+		switch {
+		case bundle.ConfigMap != nil:
+			var cm corev1.ConfigMap
+			err := r.client.Get(ctx, types.NamespacedName{Name: bundle.ConfigMap.Name, Namespace: rokku.Namespace}, &cm)
+			if errors.IsNotFound(err) {
+				if !isOptional(bundle.ConfigMap.Optional) {
+					missing = append(missing, fmt.Sprintf("ConfigMap %q", bundle.ConfigMap.Name))
+				}
+				continue
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to retrieve CA bundle ConfigMap %q: %v", bundle.ConfigMap.Name, err)
+			}
+			bundles = append(bundles, []byte(cm.Data[bundle.ConfigMap.Key]))
+		case bundle.Secret != nil:
+			var secret corev1.Secret
+			err := r.client.Get(ctx, types.NamespacedName{Name: bundle.Secret.Name, Namespace: rokku.Namespace}, &secret)
+			if errors.IsNotFound(err) {
+				if !isOptional(bundle.Secret.Optional) {
+					missing = append(missing, fmt.Sprintf("Secret %q", bundle.Secret.Name))
+				}
+				continue
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to retrieve CA bundle Secret %q: %v", bundle.Secret.Name, err)
+			}
+			bundles = append(bundles, secret.Data[bundle.Secret.Key])
+		}
+	}
+	return bundles, missing, nil
+}
+
+func isOptional(optional *bool) bool {
+	return optional != nil && *optional
+}
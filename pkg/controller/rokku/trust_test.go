@@ -0,0 +1,78 @@
+package rokku
+
+import (
+	"context"
+	"testing"
+
+	rokkuv1alpha1 "github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRefreshTrustChecksum(t *testing.T) {
+	optional := true
+	bundle := func(name string, optional *bool) rokkuv1alpha1.RokkuCABundle {
+		return rokkuv1alpha1.RokkuCABundle{ConfigMap: &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: name},
+			Key:                  "ca.pem",
+			Optional:             optional,
+		}}
+	}
+	ca := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "ca", Namespace: "default"},
+		Data:       map[string]string{"ca.pem": "-----BEGIN CERTIFICATE-----"},
+	}
+
+	tests := []struct {
+		name       string
+		bundles    []rokkuv1alpha1.RokkuCABundle
+		wantSum    bool
+		wantStatus corev1.ConditionStatus
+	}{
+		{name: "no bundles"},
+		{name: "existing bundle", bundles: []rokkuv1alpha1.RokkuCABundle{bundle("ca", nil)}, wantSum: true, wantStatus: corev1.ConditionTrue},
+		{name: "missing optional bundle", bundles: []rokkuv1alpha1.RokkuCABundle{bundle("ca", nil), bundle("other", &optional)}, wantSum: true, wantStatus: corev1.ConditionTrue},
+		{name: "missing bundle", bundles: []rokkuv1alpha1.RokkuCABundle{bundle("ca", nil), bundle("other", nil)}, wantStatus: corev1.ConditionFalse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestReconciler(ca)
+			rokku := newTestRokku()
+			if tt.bundles != nil {
+				rokku.Spec.Trust = &rokkuv1alpha1.RokkuTrust{CABundles: tt.bundles}
+			}
+
+			if err := r.refreshTrustChecksum(context.Background(), rokku); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sum := rokku.Spec.PodTemplate.Annotations["rokku.ing.com/trust-checksum"]; (sum != "") != tt.wantSum {
+				t.Errorf("checksum = %q, want one: %v", sum, tt.wantSum)
+			}
+			cond := getCondition(rokku.Status, rokkuv1alpha1.RokkuCABundlesAvailable)
+			if tt.wantStatus == "" {
+				if cond != nil {
+					t.Errorf("condition = %+v, want none", cond)
+				}
+				return
+			}
+			if cond == nil || cond.Status != tt.wantStatus {
+				t.Errorf("condition = %+v, want status %s", cond, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestReconcileMissingCABundle(t *testing.T) {
+	rokku := newTestRokku()
+	rokku.Spec.Trust = &rokkuv1alpha1.RokkuTrust{CABundles: []rokkuv1alpha1.RokkuCABundle{{
+		Secret: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "ca"}, Key: "tls.crt"},
+	}}}
+	r := newTestReconciler(rokku)
+
+	reconciled := reconcileRokku(t, r)
+	cond := getCondition(reconciled.Status, rokkuv1alpha1.RokkuCABundlesAvailable)
+	if cond == nil || cond.Status != corev1.ConditionFalse || cond.Reason != "CABundleNotFound" {
+		t.Errorf("condition = %+v, want CABundleNotFound", cond)
+	}
+}
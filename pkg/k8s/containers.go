@@ -0,0 +1,52 @@
+package k8s
+
+import (
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	appv1 "k8s.io/api/apps/v1"
+)
+
+// rokkuContainerName is the name of the container running Rokku.
+const rokkuContainerName = "rokku"
+
+// setupExtraContainers adds the user provided init containers and sidecars
+// to the pod, after the ones generated by the operator.
+func setupExtraContainers(spec v1alpha1.RokkuSpec, dep *appv1.Deployment) error {
+	podSpec := &dep.Spec.Template.Spec
+
+	names := make(map[string]bool)
+	for _, c := range podSpec.InitContainers {
+		names[c.Name] = true
+	}
+	for _, c := range podSpec.Containers {
+		names[c.Name] = true
+	}
+
+	for _, c := range spec.InitContainers {
+		if names[c.Name] {
+			return validationErrorf("init container name %q is already in use", c.Name)
+		}
+		names[c.Name] = true
+		podSpec.InitContainers = append(podSpec.InitContainers, *c.DeepCopy())
+	}
+	for _, c := range spec.Sidecars {
+		if names[c.Name] {
+			return validationErrorf("sidecar name %q is already in use", c.Name)
+		}
+		names[c.Name] = true
+		podSpec.Containers = append(podSpec.Containers, *c.DeepCopy())
+	}
+	return nil
+}
+
+// validateVolumeNames checks that the volumes of the pod template don't
+// collide with the ones generated by the operator.
+func validateVolumeNames(dep *appv1.Deployment) error {
+	names := make(map[string]bool)
+	for _, v := range dep.Spec.Template.Spec.Volumes {
+		if names[v.Name] {
+			return validationErrorf("volume name %q is already in use", v.Name)
+		}
+		names[v.Name] = true
+	}
+	return nil
+}
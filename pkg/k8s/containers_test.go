@@ -0,0 +1,99 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSetupExtraContainers(t *testing.T) {
+	trust := &v1alpha1.RokkuTrust{CABundles: []v1alpha1.RokkuCABundle{{
+		ConfigMap: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "ca"}, Key: "ca.pem"},
+	}}}
+
+	tests := []struct {
+		name           string
+		spec           v1alpha1.RokkuSpec
+		wantInit       []string
+		wantContainers []string
+		wantErr        bool
+	}{
+		{
+			name:           "none",
+			wantContainers: []string{rokkuContainerName},
+		},
+		{
+			name: "after the generated ones",
+			spec: v1alpha1.RokkuSpec{
+				Trust:          trust,
+				InitContainers: []corev1.Container{{Name: "warmer"}},
+				Sidecars:       []corev1.Container{{Name: "log-shipper"}},
+				PodTemplate: v1alpha1.RokkuPodTemplateSpec{
+					Volumes: []corev1.Volume{{Name: "logs"}},
+				},
+			},
+			wantInit:       []string{truststoreContainerName, "warmer"},
+			wantContainers: []string{rokkuContainerName, "log-shipper"},
+		},
+		{
+			name:    "sidecar named after the rokku container",
+			spec:    v1alpha1.RokkuSpec{Sidecars: []corev1.Container{{Name: rokkuContainerName}}},
+			wantErr: true,
+		},
+		{
+			name:    "init container named after the truststore builder",
+			spec:    v1alpha1.RokkuSpec{Trust: trust, InitContainers: []corev1.Container{{Name: truststoreContainerName}}},
+			wantErr: true,
+		},
+		{
+			name: "init container and sidecar of the same name",
+			spec: v1alpha1.RokkuSpec{
+				InitContainers: []corev1.Container{{Name: "helper"}},
+				Sidecars:       []corev1.Container{{Name: "helper"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "volume named after a generated one",
+			spec: v1alpha1.RokkuSpec{
+				Trust:       trust,
+				PodTemplate: v1alpha1.RokkuPodTemplateSpec{Volumes: []corev1.Volume{{Name: truststoreVolumeName}}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rokku := newTestRokku()
+			rokku.Spec = tt.spec
+
+			dep, err := NewDeployment(rokku)
+			if tt.wantErr {
+				if _, ok := err.(*ValidationError); !ok {
+					t.Fatalf("expected a ValidationError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := containerNames(dep.Spec.Template.Spec.InitContainers); !reflect.DeepEqual(got, tt.wantInit) {
+				t.Errorf("init containers = %v, want %v", got, tt.wantInit)
+			}
+			if got := containerNames(dep.Spec.Template.Spec.Containers); !reflect.DeepEqual(got, tt.wantContainers) {
+				t.Errorf("containers = %v, want %v", got, tt.wantContainers)
+			}
+		})
+	}
+}
+
+func containerNames(containers []corev1.Container) []string {
+	var names []string
+	for _, c := range containers {
+		names = append(names, c.Name)
+	}
+	return names
+}
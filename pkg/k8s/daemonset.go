@@ -0,0 +1,49 @@
+package k8s
+
+import (
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// maintenanceNodeSelectorKey is a node label no node is expected to carry.
+const maintenanceNodeSelectorKey = "rokku.ing.com/maintenance"
+
+// NewDaemonSet is the DaemonSet counterpart of NewDeployment, running a Rokku
+// pod on every node matching the pod template node selector.
+func NewDaemonSet(n *v1alpha1.Rokku) (*appv1.DaemonSet, error) {
+	deployment, err := NewDeployment(n)
+	if err != nil {
+		return nil, err
+	}
+
+	// There is nothing to scale down during the maintenance, the pods are
+	// removed by a node selector matching no node instead
+	if isMaintenanceEnabled(n) {
+		nodeSelector := map[string]string{maintenanceNodeSelectorKey: "true"}
+		for k, v := range deployment.Spec.Template.Spec.NodeSelector {
+			nodeSelector[k] = v
+		}
+		deployment.Spec.Template.Spec.NodeSelector = nodeSelector
+	}
+
+	maxUnavailable := intstr.FromInt(1)
+	return &appv1.DaemonSet{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "DaemonSet",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: deployment.ObjectMeta,
+		Spec: appv1.DaemonSetSpec{
+			Selector: deployment.Spec.Selector,
+			Template: deployment.Spec.Template,
+			UpdateStrategy: appv1.DaemonSetUpdateStrategy{
+				Type: appv1.RollingUpdateDaemonSetStrategyType,
+				RollingUpdate: &appv1.RollingUpdateDaemonSet{
+					MaxUnavailable: &maxUnavailable,
+				},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,58 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+)
+
+func TestNewDaemonSetMaintenance(t *testing.T) {
+	tests := []struct {
+		name         string
+		nodeSelector map[string]string
+		maintenance  *v1alpha1.RokkuMaintenance
+		want         map[string]string
+	}{
+		{
+			name:         "no maintenance",
+			nodeSelector: map[string]string{"pool": "edge"},
+			want:         map[string]string{"pool": "edge"},
+		},
+		{
+			name:        "maintenance disabled",
+			maintenance: &v1alpha1.RokkuMaintenance{},
+		},
+		{
+			name:        "maintenance",
+			maintenance: &v1alpha1.RokkuMaintenance{Enabled: true},
+			want:        map[string]string{maintenanceNodeSelectorKey: "true"},
+		},
+		{
+			name:         "maintenance with a node selector",
+			nodeSelector: map[string]string{"pool": "edge"},
+			maintenance:  &v1alpha1.RokkuMaintenance{Enabled: true},
+			want:         map[string]string{"pool": "edge", maintenanceNodeSelectorKey: "true"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rokku := newTestRokku()
+			rokku.Spec.WorkloadKind = v1alpha1.WorkloadKindDaemonSet
+			rokku.Spec.PodTemplate.NodeSelector = tt.nodeSelector
+			rokku.Spec.Maintenance = tt.maintenance
+
+			ds, err := NewDaemonSet(rokku)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := ds.Spec.Template.Spec.NodeSelector; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("node selector = %v, want %v", got, tt.want)
+			}
+			if tt.nodeSelector != nil && len(rokku.Spec.PodTemplate.NodeSelector) != len(tt.nodeSelector) {
+				t.Errorf("Rokku node selector was mutated: %v", rokku.Spec.PodTemplate.NodeSelector)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package k8s
+
+import (
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultNodeTopologyKey = "kubernetes.io/hostname"
+	defaultZoneTopologyKey = "topology.kubernetes.io/zone"
+)
+
+// setupHighAvailability spreads the pods of a Rokku with more than one
+// replica across nodes and zones, unless the pod template already says how
+// to do so.
+func setupHighAvailability(n *v1alpha1.Rokku, dep *appv1.Deployment) {
+	ha := n.Spec.PodTemplate.HighAvailability
+	if ha == nil {
+		ha = &v1alpha1.RokkuHighAvailability{}
+	}
+	// DaemonSets already run a single pod per node
+	if n.Spec.WorkloadKind == v1alpha1.WorkloadKindDaemonSet {
+		return
+	}
+	if ha.Disabled || n.Spec.Replicas == nil || *n.Spec.Replicas <= 1 {
+		return
+	}
+
+	podSpec := &dep.Spec.Template.Spec
+	selector := &metav1.LabelSelector{MatchLabels: LabelsForRokku(n.Name)}
+
+	if podSpec.Affinity == nil || podSpec.Affinity.PodAntiAffinity == nil {
+		// The affinity is shared with the Rokku spec, which is stored in the
+		// Deployment afterwards
+		affinity := &corev1.Affinity{}
+		if podSpec.Affinity != nil {
+			affinity = podSpec.Affinity.DeepCopy()
+		}
+		affinity.PodAntiAffinity = &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+				{
+					Weight: 100,
+					PodAffinityTerm: corev1.PodAffinityTerm{
+						LabelSelector: selector,
+						TopologyKey:   valueOrDefault(ha.NodeTopologyKey, defaultNodeTopologyKey),
+					},
+				},
+			},
+		}
+		podSpec.Affinity = affinity
+	}
+
+	if len(podSpec.TopologySpreadConstraints) == 0 {
+		var maxSkew int32 = 1
+		if ha.MaxSkew != nil {
+			maxSkew = *ha.MaxSkew
+		}
+		podSpec.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{
+			{
+				MaxSkew:           maxSkew,
+				TopologyKey:       valueOrDefault(ha.ZoneTopologyKey, defaultZoneTopologyKey),
+				WhenUnsatisfiable: corev1.ScheduleAnyway,
+				LabelSelector:     selector,
+			},
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSetupHighAvailability(t *testing.T) {
+	replicas := func(n int32) *int32 { return &n }
+	maxSkew := int32(2)
+	userAffinity := &corev1.Affinity{PodAntiAffinity: &corev1.PodAntiAffinity{}}
+
+	tests := []struct {
+		name         string
+		spec         v1alpha1.RokkuSpec
+		wantAffinity string
+		wantSpread   string
+		wantSkew     int32
+	}{
+		{name: "single replica", spec: v1alpha1.RokkuSpec{Replicas: replicas(1)}},
+		{
+			name:         "several replicas",
+			spec:         v1alpha1.RokkuSpec{Replicas: replicas(3)},
+			wantAffinity: "kubernetes.io/hostname",
+			wantSpread:   "topology.kubernetes.io/zone",
+			wantSkew:     1,
+		},
+		{
+			name: "custom topology keys",
+			spec: v1alpha1.RokkuSpec{Replicas: replicas(3), PodTemplate: v1alpha1.RokkuPodTemplateSpec{
+				HighAvailability: &v1alpha1.RokkuHighAvailability{NodeTopologyKey: "rack", ZoneTopologyKey: "region", MaxSkew: &maxSkew},
+			}},
+			wantAffinity: "rack",
+			wantSpread:   "region",
+			wantSkew:     2,
+		},
+		{
+			name: "disabled",
+			spec: v1alpha1.RokkuSpec{Replicas: replicas(3), PodTemplate: v1alpha1.RokkuPodTemplateSpec{
+				HighAvailability: &v1alpha1.RokkuHighAvailability{Disabled: true},
+			}},
+		},
+		{
+			name:       "user anti-affinity",
+			spec:       v1alpha1.RokkuSpec{Replicas: replicas(3), PodTemplate: v1alpha1.RokkuPodTemplateSpec{Affinity: userAffinity}},
+			wantSpread: "topology.kubernetes.io/zone",
+			wantSkew:   1,
+		},
+		{
+			name: "daemonset",
+			spec: v1alpha1.RokkuSpec{Replicas: replicas(3), WorkloadKind: v1alpha1.WorkloadKindDaemonSet},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rokku := newTestRokku()
+			rokku.Spec = tt.spec
+			dep, err := NewDeployment(rokku)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			podSpec := dep.Spec.Template.Spec
+
+			var affinityKey string
+			if a := podSpec.Affinity; a != nil && a.PodAntiAffinity != nil && len(a.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution) > 0 {
+				affinityKey = a.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0].PodAffinityTerm.TopologyKey
+			}
+			if affinityKey != tt.wantAffinity {
+				t.Errorf("anti-affinity topology key = %q, want %q", affinityKey, tt.wantAffinity)
+			}
+			if tt.spec.PodTemplate.Affinity != nil && podSpec.Affinity.PodAntiAffinity != userAffinity.PodAntiAffinity {
+				t.Errorf("anti-affinity = %+v, want the user one kept", podSpec.Affinity.PodAntiAffinity)
+			}
+
+			var spreadKey string
+			var skew int32
+			if c := podSpec.TopologySpreadConstraints; len(c) > 0 {
+				spreadKey, skew = c[0].TopologyKey, c[0].MaxSkew
+			}
+			if spreadKey != tt.wantSpread || skew != tt.wantSkew {
+				t.Errorf("topology spread = %q (maxSkew %d), want %q (maxSkew %d)", spreadKey, skew, tt.wantSpread, tt.wantSkew)
+			}
+		})
+	}
+}
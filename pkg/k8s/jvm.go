@@ -0,0 +1,58 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+)
+
+// validateJVM rejects the JVM options holding whitespace. JAVA_OPTS is split
+// on whitespace by the start script of the rokku image, so they would be
+// passed to the JVM as several options.
+func validateJVM(jvm *v1alpha1.RokkuJVM) error {
+	if jvm == nil {
+		return nil
+	}
+	opts := append(append([]string{}, jvm.GCOptions...), jvm.ExtraOptions...)
+	for k, v := range jvm.SystemProperties {
+		opts = append(opts, fmt.Sprintf("-D%s=%s", k, v))
+	}
+	sort.Strings(opts)
+	for _, opt := range opts {
+		if strings.IndexFunc(opt, unicode.IsSpace) >= 0 {
+			return validationErrorf("JVM option %q must not contain whitespace", opt)
+		}
+	}
+	return nil
+}
+
+// javaOpts renders the JVM options into the value of JAVA_OPTS, which the
+// start script of the rokku image passes to the JVM.
+func javaOpts(spec v1alpha1.RokkuSpec) string {
+	opts := append(trustJavaOpts(spec), proxyJavaOpts(spec)...)
+
+	jvm := spec.JVM
+	if jvm == nil {
+		return strings.Join(opts, " ")
+	}
+
+	if jvm.HeapPercentage != nil {
+		opts = append(opts, fmt.Sprintf("-XX:MaxRAMPercentage=%d.0", *jvm.HeapPercentage))
+	}
+	opts = append(opts, jvm.GCOptions...)
+
+	keys := make([]string, 0, len(jvm.SystemProperties))
+	for k := range jvm.SystemProperties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		opts = append(opts, fmt.Sprintf("-D%s=%s", k, jvm.SystemProperties[k]))
+	}
+
+	opts = append(opts, jvm.ExtraOptions...)
+	return strings.Join(opts, " ")
+}
@@ -0,0 +1,90 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+)
+
+func TestJVMOptions(t *testing.T) {
+	heap := int32(75)
+	tests := []struct {
+		name     string
+		jvm      *v1alpha1.RokkuJVM
+		wantOpts string
+		wantErr  bool
+	}{
+		{name: "no options"},
+		{
+			name: "options",
+			jvm: &v1alpha1.RokkuJVM{
+				HeapPercentage:   &heap,
+				GCOptions:        []string{"-XX:+UseG1GC"},
+				SystemProperties: map[string]string{"rokku.b": "2", "rokku.a": "1"},
+				ExtraOptions:     []string{"-XX:+ExitOnOutOfMemoryError"},
+			},
+			wantOpts: "-XX:MaxRAMPercentage=75.0 -XX:+UseG1GC -Drokku.a=1 -Drokku.b=2 -XX:+ExitOnOutOfMemoryError",
+		},
+		{
+			name:    "whitespace in an option",
+			jvm:     &v1alpha1.RokkuJVM{ExtraOptions: []string{"-Xss1m -Xmx1g"}},
+			wantErr: true,
+		},
+		{
+			name:    "whitespace in a system property",
+			jvm:     &v1alpha1.RokkuJVM{SystemProperties: map[string]string{"rokku.name": "a b"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rokku := newTestRokku()
+			rokku.Spec.JVM = tt.jvm
+
+			dep, err := NewDeployment(rokku)
+			if tt.wantErr {
+				if _, ok := err.(*ValidationError); !ok {
+					t.Fatalf("expected a ValidationError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var opts string
+			for _, e := range dep.Spec.Template.Spec.Containers[0].Env {
+				if e.Name == "JAVA_OPTS" {
+					opts = e.Value
+				}
+			}
+			if opts != tt.wantOpts {
+				t.Errorf("JAVA_OPTS = %q, want %q", opts, tt.wantOpts)
+			}
+		})
+	}
+}
+
+func TestCommandAndArgs(t *testing.T) {
+	rokku := newTestRokku()
+	rokku.Spec.Command = []string{"/wrapper.sh"}
+	rokku.Spec.Args = []string{"--verbose"}
+
+	dep, err := NewDeployment(rokku)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c := dep.Spec.Template.Spec.Containers[0]
+	if !reflect.DeepEqual(c.Command, rokku.Spec.Command) || !reflect.DeepEqual(c.Args, rokku.Spec.Args) {
+		t.Errorf("command = %v, args = %v, want %v and %v", c.Command, c.Args, rokku.Spec.Command, rokku.Spec.Args)
+	}
+
+	dep, err = NewDeployment(newTestRokku())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c := dep.Spec.Template.Spec.Containers[0]; c.Command != nil || c.Args != nil {
+		t.Errorf("command = %v, args = %v, want the image entrypoint", c.Command, c.Args)
+	}
+}
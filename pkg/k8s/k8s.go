@@ -1,10 +1,13 @@
 package k8s
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"math"
+	"net/url"
 	"os"
+	"strconv"
 
 	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
 
@@ -33,36 +36,50 @@ const (
 	defaultHTTPSHostNetworkPort = int32(443)
 	defaultHTTPSPortName        = "https"
 
-	curlProbeCommand        = "curl -m%d -kfsS -o /dev/null %s"
-	configMountPath         = "/etc/rokku"
-	generatedFromAnnotation = "rokku.ing.com/generated-from"
-	configFileName          = "ranger-s3-security.xml"
+	defaultProbeTimeoutSeconds = int32(2)
+	configMountPath            = "/etc/rokku"
+	generatedFromAnnotation    = "rokku.ing.com/generated-from"
+	lastGoodSpecAnnotation     = "rokku.ing.com/last-good-spec"
+	pausedAnnotation           = "rokku.ing.com/paused"
+	configChecksumAnnotation   = "rokku.ing.com/config-checksum"
+	configFileName             = "ranger-s3-security.xml"
+
+	defaultS3Host = "ceph-server"
+	defaultS3Port = "1234"
+	defaultSTSURI = "http://rokku-sts:8080"
 )
 
-var rokkuEntrypoint = []string{
-	"/bin/sh",
-	"-c",
-	// check what the command is
-	"/opt/entrypoint.sh",
-}
-
-var defaultPostStartCommand = []string{
-	"/bin/sh",
-	"-c",
-	// check what the command is
-	"echo Hello from the postStart handler",
-}
-
-func NewDeployment(n *v1alpha1.Rokku) (*appv1.Deployment, error) {
+// SetRokkuDefaults fills in the defaults of the Rokku spec. Specs must be
+// defaulted before being compared or hashed.
+func SetRokkuDefaults(n *v1alpha1.Rokku) {
 	n.Spec.Image = valueOrDefault(n.Spec.Image, defaultRokkuImage)
 	setDefaultPorts(&n.Spec.PodTemplate)
+	setMetricsPort(&n.Spec)
 
 	if n.Spec.Replicas == nil {
 		var one int32 = 1
 		n.Spec.Replicas = &one
 	}
 
-	securityContext := n.Spec.PodTemplate.SecurityContext
+	if IsSTSManaged(n) {
+		if n.Spec.Backends == nil {
+			n.Spec.Backends = &v1alpha1.RokkuBackends{}
+		}
+		if n.Spec.Backends.STS == "" {
+			n.Spec.Backends.STS = managedSTSURI(n)
+		}
+	}
+}
+
+func NewDeployment(n *v1alpha1.Rokku) (*appv1.Deployment, error) {
+	SetRokkuDefaults(n)
+	if err := validateJVM(n.Spec.JVM); err != nil {
+		return nil, err
+	}
+
+	// The security context is copied so that the Rokku spec, which may be
+	// passed again for the canary and color Deployments, isn't mutated
+	securityContext := n.Spec.PodTemplate.SecurityContext.DeepCopy()
 
 	if hasLowPort(n.Spec.PodTemplate.Ports) {
 		if securityContext == nil {
@@ -71,8 +88,9 @@ func NewDeployment(n *v1alpha1.Rokku) (*appv1.Deployment, error) {
 		if securityContext.Capabilities == nil {
 			securityContext.Capabilities = &corev1.Capabilities{}
 		}
-		securityContext.Capabilities.Add = append(securityContext.Capabilities.Add, "NET_BIND_SERVICE")
-
+		if !hasCapability(securityContext.Capabilities.Add, "NET_BIND_SERVICE") {
+			securityContext.Capabilities.Add = append(securityContext.Capabilities.Add, "NET_BIND_SERVICE")
+		}
 	}
 
 	var maxSurge, maxUnavailable *intstr.IntOrString
@@ -102,6 +120,7 @@ func NewDeployment(n *v1alpha1.Rokku) (*appv1.Deployment, error) {
 			},
 		},
 		Spec: appv1.DeploymentSpec{
+			ProgressDeadlineSeconds: progressDeadlineSeconds(n.Spec.Rollout),
 			Strategy: appv1.DeploymentStrategy{
 				Type: appv1.RollingUpdateDeploymentStrategyType,
 				RollingUpdate: &appv1.RollingUpdateDeployment{
@@ -109,7 +128,7 @@ func NewDeployment(n *v1alpha1.Rokku) (*appv1.Deployment, error) {
 					MaxSurge:       maxSurge,
 				},
 			},
-			Replicas: n.Spec.Replicas,
+			Replicas: DeploymentReplicas(n),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: LabelsForRokku(n.Name),
 			},
@@ -122,35 +141,29 @@ func NewDeployment(n *v1alpha1.Rokku) (*appv1.Deployment, error) {
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
 						{
-							Name:  "rokku",
-							Image: n.Spec.Image,
-							//Command:         rokkuEntrypoint,
+							Name:            rokkuContainerName,
+							Image:           n.Spec.Image,
+							Command:         n.Spec.Command,
+							Args:            n.Spec.Args,
 							Resources:       n.Spec.Resources,
 							SecurityContext: securityContext,
 							Ports:           n.Spec.PodTemplate.Ports,
 							VolumeMounts:    n.Spec.PodTemplate.VolumeMounts,
-							Env: []corev1.EnvVar{
-								{Name: "ROKKU_STORAGE_S3_HOST",
-									Value: valueOrDefault("ceph-server", os.Getenv("ROKKU_STORAGE_S3_HOST")),
-								},
-								{Name: "ROKKU_STORAGE_S3_PORT",
-									Value: valueOrDefault("1234", os.Getenv("ROKKU_STORAGE_S3_PORT"))},
-								{Name: "ROKKU_HTTP_BIND",
-									Value: valueOrDefault("8080", os.Getenv("ROKKU_HTTP_BIND"))},
-								{Name: "ROKKU_STS_URI",
-									Value: valueOrDefault("http://rokku-sts:8080", os.Getenv("ROKKU_STS_URI"))},
-								{Name: "ALLOW_LIST_BUCKETS",
-									Value: valueOrDefault("True", "False")},
-								{Name: "ALLOW_CREATE_BUCKETS",
-									Value: valueOrDefault("True", "False")},
-								{Name: "ROKKU_ATLAS_ENABLED",
-									Value: valueOrDefault("True", "False")},
-								{Name: "ROKKU_BUCKET_NOTIFY_ENABLED",
-									Value: valueOrDefault("True", "False")},
-							},
+							Env:             rokkuEnv(n.Spec),
 						},
 					},
 					Affinity:                      n.Spec.PodTemplate.Affinity,
+					NodeSelector:                  n.Spec.PodTemplate.NodeSelector,
+					Tolerations:                   n.Spec.PodTemplate.Tolerations,
+					TopologySpreadConstraints:     n.Spec.PodTemplate.TopologySpreadConstraints,
+					PriorityClassName:             n.Spec.PodTemplate.PriorityClassName,
+					ImagePullSecrets:              n.Spec.PodTemplate.ImagePullSecrets,
+					ServiceAccountName:            ServiceAccountName(n),
+					AutomountServiceAccountToken:  automountServiceAccountToken(n),
+					DNSPolicy:                     dnsPolicy(n.Spec.PodTemplate),
+					DNSConfig:                     n.Spec.PodTemplate.DNSConfig,
+					HostAliases:                   n.Spec.PodTemplate.HostAliases,
+					RuntimeClassName:              n.Spec.PodTemplate.RuntimeClassName,
 					HostNetwork:                   n.Spec.PodTemplate.HostNetwork,
 					TerminationGracePeriodSeconds: n.Spec.PodTemplate.TerminationGracePeriodSeconds,
 					Volumes:                       n.Spec.PodTemplate.Volumes,
@@ -158,10 +171,31 @@ func NewDeployment(n *v1alpha1.Rokku) (*appv1.Deployment, error) {
 			},
 		},
 	}
-	setupProbes(n.Spec, &deployment)
+	if err := setupProbes(n.Spec, &deployment); err != nil {
+		return nil, err
+	}
+	setupHighAvailability(n, &deployment)
 	setupConfig(n.Spec.Config, &deployment)
+	if err := setConfigChecksum(n.Spec.Config, &deployment); err != nil {
+		return nil, err
+	}
 	//setupConfigVolume(n.Spec.Config, &deployment)
-	setupLifecycle(n.Spec.Lifecycle, &deployment)
+	if err := setupLifecycle(n.Spec, &deployment); err != nil {
+		return nil, err
+	}
+	if err := setupTrust(n.Spec, &deployment); err != nil {
+		return nil, err
+	}
+	setupSecurityProfile(n.Spec.SecurityProfile, &deployment)
+	if err := setupExtraContainers(n.Spec, &deployment); err != nil {
+		return nil, err
+	}
+	if err := validateVolumeNames(&deployment); err != nil {
+		return nil, err
+	}
+	if err := applyDeploymentOverrides(n.Spec.Overrides, &deployment); err != nil {
+		return nil, err
+	}
 
 	// This is done on the last step because n.Spec may have mutated during these methods
 	if err := SetRokkuSpec(&deployment.ObjectMeta, n.Spec); err != nil {
@@ -171,10 +205,119 @@ func NewDeployment(n *v1alpha1.Rokku) (*appv1.Deployment, error) {
 	return &deployment, nil
 }
 
+func rokkuEnv(spec v1alpha1.RokkuSpec) []corev1.EnvVar {
+	s3Host, s3Port := s3Backend(spec)
+	env := []corev1.EnvVar{
+		{Name: "ROKKU_STORAGE_S3_HOST", Value: s3Host},
+		{Name: "ROKKU_STORAGE_S3_PORT", Value: s3Port},
+		{Name: "ROKKU_HTTP_BIND",
+			Value: valueOrDefault("8080", os.Getenv("ROKKU_HTTP_BIND"))},
+		{Name: "ROKKU_STS_URI", Value: stsBackend(spec)},
+		{Name: "ALLOW_LIST_BUCKETS",
+			Value: valueOrDefault("True", "False")},
+		{Name: "ALLOW_CREATE_BUCKETS",
+			Value: valueOrDefault("True", "False")},
+		{Name: "ROKKU_ATLAS_ENABLED",
+			Value: valueOrDefault("True", "False")},
+		{Name: "ROKKU_BUCKET_NOTIFY_ENABLED",
+			Value: valueOrDefault("True", "False")},
+	}
+	env = append(env, proxyEnv(spec)...)
+	if opts := javaOpts(spec); opts != "" {
+		env = append(env, corev1.EnvVar{Name: "JAVA_OPTS", Value: opts})
+	}
+	if spec.Backends == nil {
+		return env
+	}
+	if host, port, ok := splitURL(spec.Backends.Atlas); ok {
+		env = append(env,
+			corev1.EnvVar{Name: "ROKKU_ATLAS_API_HOST", Value: host},
+			corev1.EnvVar{Name: "ROKKU_ATLAS_API_PORT", Value: port},
+		)
+	}
+	if len(spec.Backends.Kafka) > 0 {
+		env = append(env, corev1.EnvVar{Name: "ROKKU_KAFKA_BOOTSTRAP_SERVERS", Value: strings.Join(spec.Backends.Kafka, ",")})
+	}
+	return env
+}
+
+// s3Backend returns the host and port of the S3 backend, preferring the
+// Rokku spec over the operator environment.
+func s3Backend(spec v1alpha1.RokkuSpec) (string, string) {
+	if spec.Backends != nil {
+		if host, port, ok := splitURL(spec.Backends.S3); ok {
+			return host, port
+		}
+	}
+	return valueOrDefault(os.Getenv("ROKKU_STORAGE_S3_HOST"), defaultS3Host),
+		valueOrDefault(os.Getenv("ROKKU_STORAGE_S3_PORT"), defaultS3Port)
+}
+
+// stsBackend returns the URI of the rokku-sts service.
+func stsBackend(spec v1alpha1.RokkuSpec) string {
+	if spec.Backends != nil && spec.Backends.STS != "" {
+		return spec.Backends.STS
+	}
+	return valueOrDefault(os.Getenv("ROKKU_STS_URI"), defaultSTSURI)
+}
+
+// splitURL returns the host and port of rawURL, falling back to the scheme
+// default port when none is given.
+func splitURL(rawURL string) (string, string, bool) {
+	if rawURL == "" {
+		return "", "", false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return "", "", false
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return u.Hostname(), port, true
+}
+
 func GetRokkuNameFromObject(o metav1.Object) string {
 	return o.GetLabels()["rokku.ing.com/resource-name"]
 }
 
+// IsRokkuPaused tells whether the reconciliation of the given Rokku is paused,
+// either through its spec or through the paused annotation.
+func IsRokkuPaused(n *v1alpha1.Rokku) bool {
+	return n.Spec.Paused || n.Annotations[pausedAnnotation] == "true"
+}
+
+// GetActiveColorFromObject returns the color selected by the given Rokku
+// Service when using the BlueGreen strategy.
+func GetActiveColorFromObject(o metav1.Object) string {
+	return o.GetAnnotations()[activeColorAnnotation]
+}
+
+// GetConfigChecksumFromObject returns the checksum of the configuration the
+// given pod was created with.
+func GetConfigChecksumFromObject(o metav1.Object) string {
+	return o.GetAnnotations()[configChecksumAnnotation]
+}
+
+// setSpecChecksum annotates the given Deployment with the checksum of its
+// spec, which tells whether the Deployment found in the cluster is up to date
+// without comparing the fields defaulted by the API server.
+func setSpecChecksum(dep *appv1.Deployment) error {
+	data, err := json.Marshal(dep.Spec)
+	if err != nil {
+		return err
+	}
+	if dep.Annotations == nil {
+		dep.Annotations = make(map[string]string)
+	}
+	dep.Annotations[configChecksumAnnotation] = fmt.Sprintf("%x", sha256.Sum256(data))
+	return nil
+}
+
 func valueOrDefault(value, def string) string {
 	if value != "" {
 		return value
@@ -190,6 +333,18 @@ func LabelsForRokku(name string) map[string]string {
 	}
 }
 
+// ownerReferences returns the controller reference pointing objects to their
+// Rokku owner.
+func ownerReferences(n *v1alpha1.Rokku) []metav1.OwnerReference {
+	return []metav1.OwnerReference{
+		*metav1.NewControllerRef(n, schema.GroupVersionKind{
+			Group:   v1alpha1.SchemeGroupVersion.Group,
+			Version: v1alpha1.SchemeGroupVersion.Version,
+			Kind:    "Rokku",
+		}),
+	}
+}
+
 func mergeMap(a, b map[string]string) map[string]string {
 	if a == nil {
 		return b
@@ -221,38 +376,59 @@ func portByName(ports []corev1.ContainerPort, name string) *corev1.ContainerPort
 	return nil
 }
 
-func setupLifecycle(lifecycle *v1alpha1.RokkuLifecycle, dep *appv1.Deployment) {
-	defaultLifecycle := corev1.Lifecycle{
-		PostStart: &corev1.Handler{
+func setupLifecycle(spec v1alpha1.RokkuSpec, dep *appv1.Deployment) error {
+	gracePeriod := int64(corev1.DefaultTerminationGracePeriodSeconds)
+	if spec.PodTemplate.TerminationGracePeriodSeconds != nil {
+		gracePeriod = *spec.PodTemplate.TerminationGracePeriodSeconds
+	}
+	lifecycle := &corev1.Lifecycle{
+		PreStop: &corev1.Handler{
 			Exec: &corev1.ExecAction{
-				Command: defaultPostStartCommand,
+				Command: []string{"sleep", strconv.FormatInt(gracePeriod/2, 10)},
 			},
 		},
 	}
-	dep.Spec.Template.Spec.Containers[0].Lifecycle = &defaultLifecycle
-	if lifecycle == nil {
-		return
-	}
-	if lifecycle.PreStop != nil && lifecycle.PreStop.Exec != nil {
-		dep.Spec.Template.Spec.Containers[0].Lifecycle.PreStop = &corev1.Handler{Exec: lifecycle.PreStop.Exec}
-	}
-	if lifecycle.PostStart != nil && lifecycle.PostStart.Exec != nil {
-		var postStartCommand []string
-		if len(lifecycle.PostStart.Exec.Command) > 0 {
-			lastElemIndex := len(defaultPostStartCommand) - 1
-			for i, item := range defaultPostStartCommand {
-				if i < lastElemIndex {
-					postStartCommand = append(postStartCommand, item)
-				}
+
+	if spec.Lifecycle != nil {
+		if spec.Lifecycle.PreStop != nil {
+			handler, err := lifecycleHandler(spec.Lifecycle.PreStop)
+			if err != nil {
+				return validationErrorf("invalid preStop handler: %v", err)
+			}
+			lifecycle.PreStop = handler
+		}
+		if spec.Lifecycle.PostStart != nil {
+			handler, err := lifecycleHandler(spec.Lifecycle.PostStart)
+			if err != nil {
+				return validationErrorf("invalid postStart handler: %v", err)
 			}
-			postStartCommandString := defaultPostStartCommand[lastElemIndex]
-			lifecyclePoststartCommandString := strings.Join(lifecycle.PostStart.Exec.Command, " ")
-			postStartCommand = append(postStartCommand, fmt.Sprintf("%s && %s", postStartCommandString, lifecyclePoststartCommandString))
-		} else {
-			postStartCommand = defaultPostStartCommand
+			lifecycle.PostStart = handler
 		}
-		dep.Spec.Template.Spec.Containers[0].Lifecycle.PostStart.Exec.Command = postStartCommand
 	}
+
+	dep.Spec.Template.Spec.Containers[0].Lifecycle = lifecycle
+	return nil
+}
+
+func lifecycleHandler(h *v1alpha1.RokkuLifecycleHandler) (*corev1.Handler, error) {
+	handler := &corev1.Handler{}
+	actions := 0
+	if h.Exec != nil {
+		handler.Exec = h.Exec.DeepCopy()
+		actions++
+	}
+	if h.HTTPGet != nil {
+		handler.HTTPGet = h.HTTPGet.DeepCopy()
+		actions++
+	}
+	if h.TCPSocket != nil {
+		handler.TCPSocket = h.TCPSocket.DeepCopy()
+		actions++
+	}
+	if actions != 1 {
+		return nil, validationErrorf("exactly one of exec, httpGet or tcpSocket must be set")
+	}
+	return handler, nil
 }
 
 func assembleLabels(n v1alpha1.Rokku) map[string]string {
@@ -275,6 +451,16 @@ func assembleAnnotations(n v1alpha1.Rokku) map[string]string {
 	return mergeMap(annotations, n.Spec.PodTemplate.Annotations)
 }
 
+// dnsPolicy returns the DNS policy of the rokku pod. Pods using the host's
+// network namespace would otherwise resolve through the node's resolv.conf
+// and be unable to reach the cluster services.
+func dnsPolicy(podSpec v1alpha1.RokkuPodTemplateSpec) corev1.DNSPolicy {
+	if podSpec.DNSPolicy == "" && podSpec.HostNetwork {
+		return corev1.DNSClusterFirstWithHostNet
+	}
+	return podSpec.DNSPolicy
+}
+
 func setDefaultPorts(podSpec *v1alpha1.RokkuPodTemplateSpec) {
 	if portByName(podSpec.Ports, defaultHTTPPortName) == nil {
 		httpPort := defaultHTTPPort
@@ -374,6 +560,33 @@ func setupConfig(conf *v1alpha1.ConfigRef, dep *appv1.Deployment) {
 	}
 }
 
+// SetConfigChecksum records the checksum of the config ConfigMap content into
+// the pod template annotations, so that the pods are replaced whenever the
+// ConfigMap changes.
+func SetConfigChecksum(n *v1alpha1.Rokku, checksum string) {
+	if n.Spec.PodTemplate.Annotations == nil {
+		n.Spec.PodTemplate.Annotations = make(map[string]string)
+	}
+	n.Spec.PodTemplate.Annotations[configChecksumAnnotation] = checksum
+}
+
+// setConfigChecksum records the checksum of the inline config. The checksum of
+// a ConfigMap config is resolved by the controller, see SetConfigChecksum.
+func setConfigChecksum(conf *v1alpha1.ConfigRef, dep *appv1.Deployment) error {
+	if conf == nil || conf.Kind != v1alpha1.ConfigKindInline {
+		return nil
+	}
+	data, err := json.Marshal(conf)
+	if err != nil {
+		return err
+	}
+	if dep.Spec.Template.Annotations == nil {
+		dep.Spec.Template.Annotations = make(map[string]string)
+	}
+	dep.Spec.Template.Annotations[configChecksumAnnotation] = fmt.Sprintf("%x", sha256.Sum256(data))
+	return nil
+}
+
 func setupConfigVolume(config v1alpha1.RokkuConfigSpec, dep *appv1.Deployment) {
 	if config.Path == "" {
 		return
@@ -398,6 +611,38 @@ func setupConfigVolume(config v1alpha1.RokkuConfigSpec, dep *appv1.Deployment) {
 	})
 }
 
+// SetLastGoodRokkuSpec records the spec the object was generated from as the
+// last one that was fully rolled out.
+func SetLastGoodRokkuSpec(o *metav1.ObjectMeta) bool {
+	spec, ok := o.Annotations[generatedFromAnnotation]
+	if !ok || o.Annotations[lastGoodSpecAnnotation] == spec {
+		return false
+	}
+	o.Annotations[lastGoodSpecAnnotation] = spec
+	return true
+}
+
+// ExtractLastGoodRokkuSpec returns the last spec that was fully rolled out,
+// if any.
+func ExtractLastGoodRokkuSpec(o metav1.ObjectMeta) (*v1alpha1.RokkuSpec, error) {
+	ann, ok := o.Annotations[lastGoodSpecAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var spec v1alpha1.RokkuSpec
+	if err := json.Unmarshal([]byte(ann), &spec); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rokku from annotation: %v", err)
+	}
+	return &spec, nil
+}
+
+func progressDeadlineSeconds(rollout *v1alpha1.RokkuRollout) *int32 {
+	if rollout == nil {
+		return nil
+	}
+	return rollout.ProgressDeadlineSeconds
+}
+
 func ExtractRokkuSpec(o metav1.ObjectMeta) (v1alpha1.RokkuSpec, error) {
 	ann, ok := o.Annotations[generatedFromAnnotation]
 	if !ok {
@@ -410,31 +655,88 @@ func ExtractRokkuSpec(o metav1.ObjectMeta) (v1alpha1.RokkuSpec, error) {
 	return spec, nil
 }
 
-func setupProbes(rokkuSpec v1alpha1.RokkuSpec, dep *appv1.Deployment) {
-	httpPort := portByName(rokkuSpec.PodTemplate.Ports, defaultHTTPPortName)
-	cmdTimeoutSec := int32(1)
+func setupProbes(rokkuSpec v1alpha1.RokkuSpec, dep *appv1.Deployment) error {
+	probes := rokkuSpec.Probes
+	if probes == nil {
+		probes = &v1alpha1.RokkuProbes{}
+	}
 
-	var commands []string
-	if httpPort != nil {
-		httpURL := fmt.Sprintf("http://localhost:%d%s", httpPort.ContainerPort, rokkuSpec.HealthcheckPath)
-		commands = append(commands, fmt.Sprintf(curlProbeCommand, cmdTimeoutSec, httpURL))
+	// The API server rejects liveness and startup probes with a
+	// successThreshold other than 1
+	if probes.Liveness != nil && probes.Liveness.SuccessThreshold > 1 {
+		return validationErrorf("liveness probe successThreshold must be 1, got %d", probes.Liveness.SuccessThreshold)
+	}
+	if probes.Startup != nil && probes.Startup.SuccessThreshold > 1 {
+		return validationErrorf("startup probe successThreshold must be 1, got %d", probes.Startup.SuccessThreshold)
 	}
 
-	if len(commands) == 0 {
-		return
+	portName, scheme := defaultHTTPPortName, corev1.URISchemeHTTP
+	if probes.Scheme == corev1.URISchemeHTTPS {
+		portName, scheme = defaultHTTPSPortName, corev1.URISchemeHTTPS
+	}
+	if portByName(rokkuSpec.PodTemplate.Ports, portName) == nil {
+		return nil
 	}
 
-	dep.Spec.Template.Spec.Containers[0].ReadinessProbe = &corev1.Probe{
-		TimeoutSeconds: cmdTimeoutSec * int32(len(commands)),
-		Handler: corev1.Handler{
-			Exec: &corev1.ExecAction{
-				Command: []string{
-					"sh", "-c",
-					strings.Join(commands, " && "),
-				},
-			},
+	handler := corev1.Handler{
+		HTTPGet: &corev1.HTTPGetAction{
+			Path:   valueOrDefault(rokkuSpec.HealthcheckPath, "/"),
+			Port:   intstr.FromString(portName),
+			Scheme: scheme,
 		},
 	}
+
+	container := &dep.Spec.Template.Spec.Containers[0]
+	container.ReadinessProbe = mergeProbe(corev1.Probe{
+		Handler:          handler,
+		TimeoutSeconds:   defaultProbeTimeoutSeconds,
+		PeriodSeconds:    10,
+		FailureThreshold: 3,
+	}, probes.Readiness)
+	container.LivenessProbe = mergeProbe(corev1.Probe{
+		Handler:          handler,
+		TimeoutSeconds:   defaultProbeTimeoutSeconds,
+		PeriodSeconds:    10,
+		FailureThreshold: 6,
+	}, probes.Liveness)
+	// The startup probe holds off the liveness one while the JVM is warming
+	// up, allowing up to 5 minutes to start.
+	container.StartupProbe = mergeProbe(corev1.Probe{
+		Handler:          handler,
+		TimeoutSeconds:   defaultProbeTimeoutSeconds,
+		PeriodSeconds:    10,
+		FailureThreshold: 30,
+	}, probes.Startup)
+	return nil
+}
+
+// mergeProbe applies the non-zero fields of override on top of probe.
+func mergeProbe(probe corev1.Probe, override *v1alpha1.RokkuProbe) *corev1.Probe {
+	if override == nil {
+		return &probe
+	}
+	if override.Disabled {
+		return nil
+	}
+	if override.Exec != nil || override.HTTPGet != nil || override.TCPSocket != nil {
+		probe.Handler = override.Handler
+	}
+	if override.InitialDelaySeconds != 0 {
+		probe.InitialDelaySeconds = override.InitialDelaySeconds
+	}
+	if override.TimeoutSeconds != 0 {
+		probe.TimeoutSeconds = override.TimeoutSeconds
+	}
+	if override.PeriodSeconds != 0 {
+		probe.PeriodSeconds = override.PeriodSeconds
+	}
+	if override.SuccessThreshold != 0 {
+		probe.SuccessThreshold = override.SuccessThreshold
+	}
+	if override.FailureThreshold != 0 {
+		probe.FailureThreshold = override.FailureThreshold
+	}
+	return &probe
 }
 
 // LabelsForRokkuString returns the labels in string format.
@@ -449,27 +751,54 @@ func rokkuService(n *v1alpha1.Rokku) corev1.ServiceType {
 	return corev1.ServiceType(n.Spec.Service.Type)
 }
 
-func NewService(n *v1alpha1.Rokku) *corev1.Service {
+// ServiceName returns the name of the Service for the given Rokku.
+func ServiceName(name string) string {
+	return name + "-service"
+}
+
+// ServiceDNSName returns the cluster DNS name of the given Service. The
+// cluster domain can be set through the CLUSTER_DOMAIN environment variable.
+func ServiceDNSName(name, namespace string) string {
+	return fmt.Sprintf("%s.%s.svc.%s", name, namespace, valueOrDefault(os.Getenv("CLUSTER_DOMAIN"), "cluster.local"))
+}
+
+func NewService(n *v1alpha1.Rokku) (*corev1.Service, error) {
 	var labels, annotations map[string]string
 	var lbIP string
 	var externalTrafficPolicy corev1.ServiceExternalTrafficPolicyType
 	labelSelector := LabelsForRokku(n.Name)
 	if n.Spec.Service != nil {
 		labels = n.Spec.Service.Labels
-		annotations = n.Spec.Service.Annotations
+		for k, v := range n.Spec.Service.Annotations {
+			if annotations == nil {
+				annotations = make(map[string]string)
+			}
+			annotations[k] = v
+		}
 		lbIP = n.Spec.Service.LoadBalancerIP
 		externalTrafficPolicy = n.Spec.Service.ExternalTrafficPolicy
 		if n.Spec.Service.UsePodSelector != nil && !*n.Spec.Service.UsePodSelector {
 			labelSelector = nil
 		}
 	}
+	if isBlueGreen(n) && n.Status.ActiveColor != "" {
+		// The active color is recorded along with the selector switch, so
+		// that it's never lost when the status update fails
+		if labelSelector != nil {
+			labelSelector = LabelsForRokkuColor(n.Name, n.Status.ActiveColor)
+		}
+		annotations = mergeMap(annotations, map[string]string{activeColorAnnotation: n.Status.ActiveColor})
+	}
+	if isMaintenanceEnabled(n) && labelSelector != nil {
+		labelSelector = LabelsForMaintenance(n.Name)
+	}
 	service := corev1.Service{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Service",
 			APIVersion: "v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      n.Name + "-service",
+			Name:      ServiceName(n.Name),
 			Namespace: n.Namespace,
 			OwnerReferences: []metav1.OwnerReference{
 				*metav1.NewControllerRef(n, schema.GroupVersionKind{
@@ -502,5 +831,16 @@ func NewService(n *v1alpha1.Rokku) *corev1.Service {
 			ExternalTrafficPolicy: externalTrafficPolicy,
 		},
 	}
-	return &service
+	if n.Spec.Monitoring != nil {
+		service.Spec.Ports = append(service.Spec.Ports, corev1.ServicePort{
+			Name:       defaultMetricsPortName,
+			Protocol:   corev1.ProtocolTCP,
+			TargetPort: intstr.FromString(defaultMetricsPortName),
+			Port:       metricsServicePort(n),
+		})
+	}
+	if err := applyServiceOverrides(n.Spec.Overrides, &service); err != nil {
+		return nil, err
+	}
+	return &service, nil
 }
@@ -0,0 +1,233 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newTestRokku returns a Rokku with an empty spec, as submitted before its
+// defaults are set.
+func newTestRokku() *v1alpha1.Rokku {
+	return &v1alpha1.Rokku{
+		ObjectMeta: metav1.ObjectMeta{Name: "rokku", Namespace: "default"},
+	}
+}
+
+func TestSetupProbes(t *testing.T) {
+	tests := []struct {
+		name    string
+		probes  *v1alpha1.RokkuProbes
+		check   func(t *testing.T, c corev1.Container)
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, c corev1.Container) {
+				for probe, p := range map[string]*corev1.Probe{"readiness": c.ReadinessProbe, "liveness": c.LivenessProbe, "startup": c.StartupProbe} {
+					if p == nil || p.HTTPGet == nil || p.HTTPGet.Port.StrVal != "http" || p.HTTPGet.Path != "/" {
+						t.Errorf("%s probe = %+v, want an HTTP GET / on the http port", probe, p)
+					}
+				}
+				if c.StartupProbe.FailureThreshold != 30 {
+					t.Errorf("startup probe failureThreshold = %d, want 30", c.StartupProbe.FailureThreshold)
+				}
+			},
+		},
+		{
+			name: "overrides",
+			probes: &v1alpha1.RokkuProbes{
+				Readiness: &v1alpha1.RokkuProbe{Probe: corev1.Probe{SuccessThreshold: 3, PeriodSeconds: 5}},
+				Liveness:  &v1alpha1.RokkuProbe{Probe: corev1.Probe{SuccessThreshold: 1}},
+				Startup:   &v1alpha1.RokkuProbe{Disabled: true},
+			},
+			check: func(t *testing.T, c corev1.Container) {
+				if p := c.ReadinessProbe; p.SuccessThreshold != 3 || p.PeriodSeconds != 5 || p.FailureThreshold != 3 {
+					t.Errorf("readiness probe = %+v, want the overrides merged into the defaults", p)
+				}
+				if c.LivenessProbe.SuccessThreshold != 1 {
+					t.Errorf("liveness probe successThreshold = %d, want 1", c.LivenessProbe.SuccessThreshold)
+				}
+				if c.StartupProbe != nil {
+					t.Errorf("startup probe = %+v, want none", c.StartupProbe)
+				}
+			},
+		},
+		{
+			name:    "liveness successThreshold",
+			probes:  &v1alpha1.RokkuProbes{Liveness: &v1alpha1.RokkuProbe{Probe: corev1.Probe{SuccessThreshold: 2}}},
+			wantErr: true,
+		},
+		{
+			name:    "startup successThreshold",
+			probes:  &v1alpha1.RokkuProbes{Startup: &v1alpha1.RokkuProbe{Probe: corev1.Probe{SuccessThreshold: 2}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rokku := newTestRokku()
+			rokku.Spec.Probes = tt.probes
+
+			dep, err := NewDeployment(rokku)
+			if tt.wantErr {
+				if _, ok := err.(*ValidationError); !ok {
+					t.Fatalf("expected a ValidationError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.check(t, dep.Spec.Template.Spec.Containers[0])
+		})
+	}
+}
+
+func TestSetupLifecycle(t *testing.T) {
+	grace := int64(60)
+	tests := []struct {
+		name      string
+		lifecycle *v1alpha1.RokkuLifecycle
+		check     func(t *testing.T, l *corev1.Lifecycle)
+		wantErr   bool
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, l *corev1.Lifecycle) {
+				if l.PreStop == nil || l.PreStop.Exec == nil || !reflect.DeepEqual(l.PreStop.Exec.Command, []string{"sleep", "30"}) {
+					t.Errorf("preStop = %+v, want sleeping half of the grace period", l.PreStop)
+				}
+				if l.PostStart != nil {
+					t.Errorf("postStart = %+v, want none", l.PostStart)
+				}
+			},
+		},
+		{
+			name: "overrides",
+			lifecycle: &v1alpha1.RokkuLifecycle{
+				PreStop:   &v1alpha1.RokkuLifecycleHandler{HTTPGet: &corev1.HTTPGetAction{Path: "/drain"}},
+				PostStart: &v1alpha1.RokkuLifecycleHandler{Exec: &corev1.ExecAction{Command: []string{"warmup"}}},
+			},
+			check: func(t *testing.T, l *corev1.Lifecycle) {
+				if l.PreStop.Exec != nil || l.PreStop.HTTPGet == nil || l.PreStop.HTTPGet.Path != "/drain" {
+					t.Errorf("preStop = %+v, want the HTTP GET /drain", l.PreStop)
+				}
+				if l.PostStart == nil || l.PostStart.Exec == nil {
+					t.Errorf("postStart = %+v, want the exec action", l.PostStart)
+				}
+			},
+		},
+		{
+			name:      "no action",
+			lifecycle: &v1alpha1.RokkuLifecycle{PreStop: &v1alpha1.RokkuLifecycleHandler{}},
+			wantErr:   true,
+		},
+		{
+			name: "several actions",
+			lifecycle: &v1alpha1.RokkuLifecycle{PostStart: &v1alpha1.RokkuLifecycleHandler{
+				Exec:      &corev1.ExecAction{Command: []string{"warmup"}},
+				TCPSocket: &corev1.TCPSocketAction{},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rokku := newTestRokku()
+			rokku.Spec.PodTemplate.TerminationGracePeriodSeconds = &grace
+			rokku.Spec.Lifecycle = tt.lifecycle
+
+			dep, err := NewDeployment(rokku)
+			if tt.wantErr {
+				if _, ok := err.(*ValidationError); !ok {
+					t.Fatalf("expected a ValidationError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tt.check(t, dep.Spec.Template.Spec.Containers[0].Lifecycle)
+		})
+	}
+}
+
+func TestSchedulingControls(t *testing.T) {
+	runtimeClass := "gvisor"
+	template := v1alpha1.RokkuPodTemplateSpec{
+		NodeSelector:              map[string]string{"role": "storage"},
+		Tolerations:               []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}},
+		TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{MaxSkew: 2, TopologyKey: "rack", WhenUnsatisfiable: corev1.DoNotSchedule}},
+		PriorityClassName:         "high",
+		ImagePullSecrets:          []corev1.LocalObjectReference{{Name: "registry"}},
+		ServiceAccountName:        "existing",
+		DNSConfig:                 &corev1.PodDNSConfig{Searches: []string{"example.com"}},
+		HostAliases:               []corev1.HostAlias{{IP: "10.0.0.1", Hostnames: []string{"ceph"}}},
+		RuntimeClassName:          &runtimeClass,
+	}
+	rokku := newTestRokku()
+	rokku.Spec.PodTemplate = template
+
+	dep, err := NewDeployment(rokku)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := corev1.PodSpec{
+		NodeSelector:              template.NodeSelector,
+		Tolerations:               template.Tolerations,
+		TopologySpreadConstraints: template.TopologySpreadConstraints,
+		PriorityClassName:         template.PriorityClassName,
+		ImagePullSecrets:          template.ImagePullSecrets,
+		ServiceAccountName:        template.ServiceAccountName,
+		DNSConfig:                 template.DNSConfig,
+		HostAliases:               template.HostAliases,
+		RuntimeClassName:          template.RuntimeClassName,
+	}
+	pod := dep.Spec.Template.Spec
+	got := corev1.PodSpec{
+		NodeSelector:              pod.NodeSelector,
+		Tolerations:               pod.Tolerations,
+		TopologySpreadConstraints: pod.TopologySpreadConstraints,
+		PriorityClassName:         pod.PriorityClassName,
+		ImagePullSecrets:          pod.ImagePullSecrets,
+		ServiceAccountName:        pod.ServiceAccountName,
+		DNSConfig:                 pod.DNSConfig,
+		HostAliases:               pod.HostAliases,
+		RuntimeClassName:          pod.RuntimeClassName,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pod spec = %+v, want %+v", got, want)
+	}
+}
+
+func TestDNSPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		template v1alpha1.RokkuPodTemplateSpec
+		want     corev1.DNSPolicy
+	}{
+		{name: "default"},
+		{name: "host network", template: v1alpha1.RokkuPodTemplateSpec{HostNetwork: true}, want: corev1.DNSClusterFirstWithHostNet},
+		{name: "explicit", template: v1alpha1.RokkuPodTemplateSpec{HostNetwork: true, DNSPolicy: corev1.DNSDefault}, want: corev1.DNSDefault},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rokku := newTestRokku()
+			rokku.Spec.PodTemplate = tt.template
+			dep, err := NewDeployment(rokku)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := dep.Spec.Template.Spec.DNSPolicy; got != tt.want {
+				t.Errorf("dnsPolicy = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
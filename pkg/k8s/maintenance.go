@@ -0,0 +1,163 @@
+package k8s
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultMaintenanceImage   = "nginxinc/nginx-unprivileged:stable-alpine"
+	defaultMaintenanceMessage = "Please reduce your request rate. The service is under maintenance."
+	maintenanceConfigKey      = "default.conf"
+
+	// The responder answers plain HTTP on both ports, so clients reaching the
+	// https Service port fail the TLS handshake instead of getting the error.
+	maintenanceConfig = `server {
+    listen %d;
+    listen %d;
+    location / {
+        default_type application/xml;
+        add_header Retry-After 120 always;
+        return 503 '<?xml version="1.0" encoding="UTF-8"?>
+<Error><Code>SlowDown</Code><Message>%s</Message><Resource>$uri</Resource><RequestId>$request_id</RequestId></Error>';
+    }
+}
+`
+)
+
+// MaintenanceName returns the name of the maintenance responder objects for
+// the given Rokku.
+func MaintenanceName(name string) string {
+	return name + "-maintenance"
+}
+
+// LabelsForMaintenance returns the labels of the maintenance responder pods.
+// They don't match LabelsForRokku so that the responder is neither counted as
+// a Rokku pod nor selected by the Rokku Deployments.
+func LabelsForMaintenance(name string) map[string]string {
+	return map[string]string{
+		"rokku.ing.com/resource-name": name,
+		"rokku.ing.com/app":           "rokku-maintenance",
+	}
+}
+
+func isMaintenanceEnabled(n *v1alpha1.Rokku) bool {
+	return n.Spec.Maintenance != nil && n.Spec.Maintenance.Enabled
+}
+
+// DeploymentReplicas returns the number of Rokku replicas, which is reduced
+// during the maintenance.
+func DeploymentReplicas(n *v1alpha1.Rokku) *int32 {
+	if !isMaintenanceEnabled(n) {
+		return n.Spec.Replicas
+	}
+	if n.Spec.Maintenance.Replicas != nil {
+		return n.Spec.Maintenance.Replicas
+	}
+	var zero int32
+	return &zero
+}
+
+// NewMaintenanceConfigMap returns the ConfigMap holding the nginx config of the
+// maintenance responder.
+func NewMaintenanceConfigMap(n *v1alpha1.Rokku) *corev1.ConfigMap {
+	var message bytes.Buffer
+	xml.EscapeText(&message, []byte(valueOrDefault(n.Spec.Maintenance.Message, defaultMaintenanceMessage)))
+	// nginx would expand variables found in the message
+	escaped := strings.Replace(message.String(), "$", "&#36;", -1)
+
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ConfigMap",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            MaintenanceName(n.Name),
+			Namespace:       n.Namespace,
+			OwnerReferences: ownerReferences(n),
+			Labels:          LabelsForMaintenance(n.Name),
+		},
+		Data: map[string]string{
+			maintenanceConfigKey: fmt.Sprintf(maintenanceConfig, defaultHTTPPort, defaultHTTPSPort, escaped),
+		},
+	}
+}
+
+// NewMaintenanceDeployment returns the Deployment of the maintenance responder.
+// It's annotated with the checksum of its spec, which tells whether the
+// Deployment is up to date.
+func NewMaintenanceDeployment(n *v1alpha1.Rokku) (*appv1.Deployment, error) {
+	var one int32 = 1
+	config := NewMaintenanceConfigMap(n)
+	deployment := &appv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Deployment",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            MaintenanceName(n.Name),
+			Namespace:       n.Namespace,
+			OwnerReferences: ownerReferences(n),
+			Labels:          LabelsForMaintenance(n.Name),
+		},
+		Spec: appv1.DeploymentSpec{
+			Replicas: &one,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: LabelsForMaintenance(n.Name),
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: LabelsForMaintenance(n.Name),
+					// nginx doesn't reload its config, so the pods are
+					// replaced whenever it changes
+					Annotations: map[string]string{
+						configChecksumAnnotation: fmt.Sprintf("%x", sha256.Sum256([]byte(config.Data[maintenanceConfigKey]))),
+					},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "maintenance",
+							Image: valueOrDefault(n.Spec.Maintenance.Image, defaultMaintenanceImage),
+							Ports: []corev1.ContainerPort{
+								{Name: defaultHTTPPortName, ContainerPort: defaultHTTPPort, Protocol: corev1.ProtocolTCP},
+								{Name: defaultHTTPSPortName, ContainerPort: defaultHTTPSPort, Protocol: corev1.ProtocolTCP},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{Name: "maintenance-config", MountPath: "/etc/nginx/conf.d"},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "maintenance-config",
+							VolumeSource: corev1.VolumeSource{
+								ConfigMap: &corev1.ConfigMapVolumeSource{
+									LocalObjectReference: corev1.LocalObjectReference{Name: MaintenanceName(n.Name)},
+								},
+							},
+						},
+					},
+					// The responder is scheduled next to the Rokku pods it
+					// stands in for
+					NodeSelector:      n.Spec.PodTemplate.NodeSelector,
+					Tolerations:       n.Spec.PodTemplate.Tolerations,
+					PriorityClassName: n.Spec.PodTemplate.PriorityClassName,
+					ImagePullSecrets:  n.Spec.PodTemplate.ImagePullSecrets,
+				},
+			},
+		},
+	}
+	if err := setSpecChecksum(deployment); err != nil {
+		return nil, err
+	}
+	return deployment, nil
+}
@@ -0,0 +1,75 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewMaintenanceDeployment(t *testing.T) {
+	newMaintenanceRokku := func() *v1alpha1.Rokku {
+		rokku := newTestRokku()
+		rokku.Spec.Maintenance = &v1alpha1.RokkuMaintenance{Enabled: true}
+		return rokku
+	}
+	checksum := func(t *testing.T, rokku *v1alpha1.Rokku) string {
+		deploy, err := NewMaintenanceDeployment(rokku)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		sum := GetConfigChecksumFromObject(deploy)
+		if sum == "" {
+			t.Fatal("expected a spec checksum")
+		}
+		return sum
+	}
+	base := checksum(t, newMaintenanceRokku())
+
+	tests := []struct {
+		name   string
+		mutate func(*v1alpha1.Rokku)
+		same   bool
+	}{
+		{name: "rokku image", mutate: func(n *v1alpha1.Rokku) { n.Spec.Image = "wbaa/rokku:2" }, same: true},
+		{name: "rokku replicas during maintenance", mutate: func(n *v1alpha1.Rokku) {
+			two := int32(2)
+			n.Spec.Maintenance.Replicas = &two
+		}, same: true},
+		{name: "responder image", mutate: func(n *v1alpha1.Rokku) { n.Spec.Maintenance.Image = "nginx:1" }},
+		{name: "message", mutate: func(n *v1alpha1.Rokku) { n.Spec.Maintenance.Message = "Back soon" }},
+		{name: "node selector", mutate: func(n *v1alpha1.Rokku) {
+			n.Spec.PodTemplate.NodeSelector = map[string]string{"pool": "edge"}
+		}},
+		{name: "tolerations", mutate: func(n *v1alpha1.Rokku) {
+			n.Spec.PodTemplate.Tolerations = []corev1.Toleration{{Key: "edge", Operator: corev1.TolerationOpExists}}
+		}},
+		{name: "image pull secrets", mutate: func(n *v1alpha1.Rokku) {
+			n.Spec.PodTemplate.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "registry"}}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rokku := newMaintenanceRokku()
+			tt.mutate(rokku)
+			if sum := checksum(t, rokku); (sum == base) != tt.same {
+				t.Errorf("checksum equality = %v, want %v", sum == base, tt.same)
+			}
+		})
+	}
+}
+
+func TestNewMaintenanceConfigMap(t *testing.T) {
+	rokku := newTestRokku()
+	rokku.Spec.Maintenance = &v1alpha1.RokkuMaintenance{Enabled: true, Message: "Costs <$5> & more"}
+
+	config := NewMaintenanceConfigMap(rokku).Data[maintenanceConfigKey]
+	if !strings.Contains(config, "<Message>Costs &lt;&#36;5&gt; &amp; more</Message>") {
+		t.Errorf("config must hold the escaped message, got:\n%s", config)
+	}
+	if strings.Contains(config, "$5") {
+		t.Errorf("nginx variables must be escaped, got:\n%s", config)
+	}
+}
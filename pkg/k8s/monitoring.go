@@ -0,0 +1,284 @@
+package k8s
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	defaultMetricsPort     = int32(9404)
+	defaultMetricsPortName = "metrics"
+	defaultMetricsPath     = "/metrics"
+)
+
+// MonitorName returns the name of the ServiceMonitor or PodMonitor for the
+// given Rokku.
+func MonitorName(name string) string {
+	return name + "-monitor"
+}
+
+// NewServiceMonitor returns the ServiceMonitor scraping the metrics port of
+// the Rokku Service.
+func NewServiceMonitor(n *v1alpha1.Rokku) *monitoringv1.ServiceMonitor {
+	return &monitoringv1.ServiceMonitor{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       monitoringv1.ServiceMonitorsKind,
+			APIVersion: monitoringv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: monitorObjectMeta(n, MonitorName(n.Name)),
+		Spec: monitoringv1.ServiceMonitorSpec{
+			Selector: metav1.LabelSelector{
+				MatchLabels: LabelsForRokku(n.Name),
+			},
+			Endpoints: []monitoringv1.Endpoint{
+				{
+					Port:     defaultMetricsPortName,
+					Path:     valueOrDefault(n.Spec.Monitoring.Path, defaultMetricsPath),
+					Interval: n.Spec.Monitoring.Interval,
+				},
+			},
+		},
+	}
+}
+
+// NewPodMonitor returns the PodMonitor scraping the metrics port of the Rokku
+// pods.
+func NewPodMonitor(n *v1alpha1.Rokku) *monitoringv1.PodMonitor {
+	return &monitoringv1.PodMonitor{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       monitoringv1.PodMonitorsKind,
+			APIVersion: monitoringv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: monitorObjectMeta(n, MonitorName(n.Name)),
+		Spec: monitoringv1.PodMonitorSpec{
+			Selector: metav1.LabelSelector{
+				MatchLabels: LabelsForRokku(n.Name),
+			},
+			PodMetricsEndpoints: []monitoringv1.PodMetricsEndpoint{
+				{
+					Port:     defaultMetricsPortName,
+					Path:     valueOrDefault(n.Spec.Monitoring.Path, defaultMetricsPath),
+					Interval: n.Spec.Monitoring.Interval,
+				},
+			},
+		},
+	}
+}
+
+func monitorObjectMeta(n *v1alpha1.Rokku, name string) metav1.ObjectMeta {
+	labels := make(map[string]string)
+	for k, v := range n.Spec.Monitoring.Labels {
+		labels[k] = v
+	}
+	return metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       n.Namespace,
+		OwnerReferences: ownerReferences(n),
+		Labels:          mergeMap(labels, LabelsForRokku(n.Name)),
+	}
+}
+
+// setMetricsPort adds the metrics port to the Rokku ports when monitoring is
+// enabled.
+func setMetricsPort(spec *v1alpha1.RokkuSpec) {
+	if spec.Monitoring == nil || portByName(spec.PodTemplate.Ports, defaultMetricsPortName) != nil {
+		return
+	}
+	port := spec.Monitoring.Port
+	if port == 0 {
+		port = defaultMetricsPort
+	}
+	spec.PodTemplate.Ports = append(spec.PodTemplate.Ports, corev1.ContainerPort{
+		Name:          defaultMetricsPortName,
+		ContainerPort: port,
+		Protocol:      corev1.ProtocolTCP,
+	})
+}
+
+func metricsServicePort(n *v1alpha1.Rokku) int32 {
+	if port := portByName(n.Spec.PodTemplate.Ports, defaultMetricsPortName); port != nil {
+		return port.ContainerPort
+	}
+	if n.Spec.Monitoring.Port != 0 {
+		return n.Spec.Monitoring.Port
+	}
+	return defaultMetricsPort
+}
+
+// Metrics used by the standard Rokku alerts.
+const (
+	httpResponsesMetric       = "rokku_http_responses_total"
+	stsErrorsMetric           = "rokku_sts_request_errors_total"
+	rangerPolicyRefreshMetric = "rokku_ranger_policy_last_refresh_timestamp_seconds"
+	deploymentAvailableMetric = "kube_deployment_status_replicas_available"
+	deploymentReplicasMetric  = "kube_deployment_spec_replicas"
+	daemonSetAvailableMetric  = "kube_daemonset_status_number_available"
+	daemonSetDesiredMetric    = "kube_daemonset_status_desired_number_scheduled"
+	defaultAlertSeverity      = "warning"
+	defaultCriticalSeverity   = "critical"
+)
+
+// standardAlert holds the defaults of a standard alert. expr must contain a
+// single %s verb which is replaced by the threshold.
+type standardAlert struct {
+	name      string
+	expr      string
+	threshold string
+	forPeriod string
+	severity  string
+	summary   string
+	override  *v1alpha1.RokkuAlert
+}
+
+// PrometheusRuleName returns the name of the PrometheusRule for the given
+// Rokku.
+func PrometheusRuleName(name string) string {
+	return name + "-alerts"
+}
+
+// podNamePattern returns the regular expression matching the names of the
+// Rokku pods of the active workload only. It's anchored on the generated
+// suffixes, so that it matches neither the canary or rokku-sts pods nor the
+// pods of another Rokku whose name starts with the same prefix.
+func podNamePattern(n *v1alpha1.Rokku) string {
+	name := regexp.QuoteMeta(n.Name)
+	switch {
+	case n.Spec.WorkloadKind == v1alpha1.WorkloadKindDaemonSet:
+		return name + "-[a-z0-9]{5}"
+	case isBlueGreen(n):
+		return name + "-(blue|green)-[a-z0-9]+-[a-z0-9]{5}"
+	}
+	return name + "-[a-z0-9]+-[a-z0-9]{5}"
+}
+
+// unavailablePodsExpr returns the PromQL expression counting the unavailable
+// pods of the workload used by the active mode.
+func unavailablePodsExpr(n *v1alpha1.Rokku) string {
+	desired, available := deploymentReplicasMetric, deploymentAvailableMetric
+	selector := fmt.Sprintf(`namespace=%q,deployment=%q`, n.Namespace, n.Name)
+	switch {
+	case n.Spec.WorkloadKind == v1alpha1.WorkloadKindDaemonSet:
+		desired, available = daemonSetDesiredMetric, daemonSetAvailableMetric
+		selector = fmt.Sprintf(`namespace=%q,daemonset=%q`, n.Namespace, n.Name)
+	case isBlueGreen(n):
+		selector = fmt.Sprintf(`namespace=%q,deployment=~"%s-(blue|green)"`, n.Namespace, regexp.QuoteMeta(n.Name))
+	}
+	return fmt.Sprintf("sum(%s{%s}) - sum(%s{%s})", desired, selector, available, selector)
+}
+
+func isBlueGreen(n *v1alpha1.Rokku) bool {
+	return n.Spec.Rollout != nil && n.Spec.Rollout.Strategy == v1alpha1.RolloutStrategyBlueGreen
+}
+
+// NewPrometheusRule returns the PrometheusRule holding the standard Rokku
+// alerts configured in spec.monitoring.alerts.
+func NewPrometheusRule(n *v1alpha1.Rokku) (*monitoringv1.PrometheusRule, error) {
+	alerts := n.Spec.Monitoring.Alerts
+	podSelector := fmt.Sprintf(`namespace=%q,pod=~"%s"`, n.Namespace, podNamePattern(n))
+
+	instance := n.Namespace + "/" + n.Name
+	defaults := []standardAlert{
+		{
+			name:      "RokkuPodsNotReady",
+			expr:      unavailablePodsExpr(n) + " > %s",
+			threshold: "0",
+			forPeriod: "10m",
+			severity:  defaultAlertSeverity,
+			summary:   fmt.Sprintf("Rokku %s has pods that are not ready.", instance),
+			override:  alerts.PodsNotReady,
+		},
+		{
+			name: "RokkuHighErrorRate",
+			expr: fmt.Sprintf(`sum(rate(%s{%s,code=~"5.."}[5m])) / sum(rate(%s{%s}[5m])) > %%s`,
+				httpResponsesMetric, podSelector, httpResponsesMetric, podSelector),
+			threshold: "0.05",
+			forPeriod: "10m",
+			severity:  defaultCriticalSeverity,
+			summary:   fmt.Sprintf("Rokku %s is answering with a high rate of 5xx errors.", instance),
+			override:  alerts.HighErrorRate,
+		},
+		{
+			name:      "RokkuSTSUnreachable",
+			expr:      fmt.Sprintf("sum(rate(%s{%s}[5m])) > %%s", stsErrorsMetric, podSelector),
+			threshold: "0",
+			forPeriod: "5m",
+			severity:  defaultCriticalSeverity,
+			summary:   fmt.Sprintf("Rokku %s can't reach the STS service.", instance),
+			override:  alerts.STSUnreachable,
+		},
+		{
+			name:      "RokkuRangerPolicyRefreshFailing",
+			expr:      fmt.Sprintf("time() - max(%s{%s}) > %%s", rangerPolicyRefreshMetric, podSelector),
+			threshold: "600",
+			forPeriod: "5m",
+			severity:  defaultAlertSeverity,
+			summary:   fmt.Sprintf("Rokku %s is failing to refresh the Ranger policies.", instance),
+			override:  alerts.RangerPolicyRefreshFailing,
+		},
+	}
+
+	rules := []monitoringv1.Rule{}
+	for _, d := range defaults {
+		threshold, forPeriod, severity := d.threshold, d.forPeriod, d.severity
+		if o := d.override; o != nil {
+			if o.Disabled {
+				continue
+			}
+			if o.Threshold != "" {
+				// The threshold is interpolated into PromQL, only a number is
+				// accepted
+				if _, err := strconv.ParseFloat(o.Threshold, 64); err != nil {
+					return nil, validationErrorf("invalid threshold %q of alert %s: must be a number", o.Threshold, d.name)
+				}
+				threshold = o.Threshold
+			}
+			forPeriod = valueOrDefault(o.For, forPeriod)
+			severity = valueOrDefault(o.Severity, severity)
+		}
+		labels := map[string]string{}
+		for k, v := range alerts.Labels {
+			labels[k] = v
+		}
+		labels["severity"] = severity
+		labels["rokku_name"] = n.Name
+		labels["rokku_namespace"] = n.Namespace
+		rules = append(rules, monitoringv1.Rule{
+			Alert:       d.name,
+			Expr:        intstr.FromString(fmt.Sprintf(d.expr, threshold)),
+			For:         forPeriod,
+			Labels:      labels,
+			Annotations: map[string]string{"summary": d.summary},
+		})
+	}
+
+	return &monitoringv1.PrometheusRule{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       monitoringv1.PrometheusRuleKind,
+			APIVersion: monitoringv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: monitorObjectMeta(n, PrometheusRuleName(n.Name)),
+		Spec: monitoringv1.PrometheusRuleSpec{
+			Groups: []monitoringv1.RuleGroup{
+				{
+					Name:  fmt.Sprintf("rokku.%s.%s", n.Namespace, n.Name),
+					Rules: rules,
+				},
+			},
+		},
+	}, nil
+}
+
+// CanaryErrorRateQuery returns the PromQL query computing the ratio of 5xx
+// responses served by the canary pods of the given Rokku.
+func CanaryErrorRateQuery(n *v1alpha1.Rokku) string {
+	podSelector := fmt.Sprintf(`namespace=%q,pod=~"%s-.*"`, n.Namespace, CanaryName(n.Name))
+	return fmt.Sprintf(`sum(rate(%s{%s,code=~"5.."}[1m])) / sum(rate(%s{%s}[1m]))`,
+		httpResponsesMetric, podSelector, httpResponsesMetric, podSelector)
+}
@@ -0,0 +1,176 @@
+package k8s
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+)
+
+func TestPodNamePattern(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(*v1alpha1.Rokku)
+		match    []string
+		notMatch []string
+	}{
+		{
+			name:     "deployment",
+			match:    []string{"rokku-5d8f7c9b4-x2k9p"},
+			notMatch: []string{"rokku-canary-5d8f7c9b4-x2k9p", "rokku-sts-5d8f7c9b4-x2k9p", "rokku-eu-5d8f7c9b4-x2k9p", "rokku-x2k9p", "rokku-maintenance-5d8f7c9b4-x2k9p"},
+		},
+		{
+			name: "daemonset",
+			mutate: func(n *v1alpha1.Rokku) {
+				n.Spec.WorkloadKind = v1alpha1.WorkloadKindDaemonSet
+			},
+			match:    []string{"rokku-x2k9p"},
+			notMatch: []string{"rokku-5d8f7c9b4-x2k9p", "rokku-sts-x2k9p"},
+		},
+		{
+			name: "blue/green",
+			mutate: func(n *v1alpha1.Rokku) {
+				n.Spec.Rollout = &v1alpha1.RokkuRollout{Strategy: v1alpha1.RolloutStrategyBlueGreen}
+			},
+			match:    []string{"rokku-blue-5d8f7c9b4-x2k9p", "rokku-green-5d8f7c9b4-x2k9p"},
+			notMatch: []string{"rokku-5d8f7c9b4-x2k9p", "rokku-red-5d8f7c9b4-x2k9p"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rokku := newTestRokku()
+			if tt.mutate != nil {
+				tt.mutate(rokku)
+			}
+			// Prometheus regular expressions are fully anchored
+			re := regexp.MustCompile("^(?:" + podNamePattern(rokku) + ")$")
+			for _, pod := range tt.match {
+				if !re.MatchString(pod) {
+					t.Errorf("pattern %q must match %q", re, pod)
+				}
+			}
+			for _, pod := range tt.notMatch {
+				if re.MatchString(pod) {
+					t.Errorf("pattern %q must not match %q", re, pod)
+				}
+			}
+		})
+	}
+}
+
+func TestUnavailablePodsExpr(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*v1alpha1.Rokku)
+		want   string
+	}{
+		{
+			name: "deployment",
+			want: `sum(kube_deployment_spec_replicas{namespace="default",deployment="rokku"}) - sum(kube_deployment_status_replicas_available{namespace="default",deployment="rokku"})`,
+		},
+		{
+			name: "daemonset",
+			mutate: func(n *v1alpha1.Rokku) {
+				n.Spec.WorkloadKind = v1alpha1.WorkloadKindDaemonSet
+			},
+			want: `sum(kube_daemonset_status_desired_number_scheduled{namespace="default",daemonset="rokku"}) - sum(kube_daemonset_status_number_available{namespace="default",daemonset="rokku"})`,
+		},
+		{
+			name: "blue/green",
+			mutate: func(n *v1alpha1.Rokku) {
+				n.Spec.Rollout = &v1alpha1.RokkuRollout{Strategy: v1alpha1.RolloutStrategyBlueGreen}
+			},
+			want: `sum(kube_deployment_spec_replicas{namespace="default",deployment=~"rokku-(blue|green)"}) - sum(kube_deployment_status_replicas_available{namespace="default",deployment=~"rokku-(blue|green)"})`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rokku := newTestRokku()
+			if tt.mutate != nil {
+				tt.mutate(rokku)
+			}
+			if got := unavailablePodsExpr(rokku); got != tt.want {
+				t.Errorf("unavailablePodsExpr() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPrometheusRule(t *testing.T) {
+	tests := []struct {
+		name      string
+		alerts    v1alpha1.RokkuAlerts
+		wantRules map[string]string
+		wantErr   bool
+	}{
+		{
+			name: "defaults",
+			wantRules: map[string]string{
+				"RokkuPodsNotReady":               "> 0",
+				"RokkuHighErrorRate":              "> 0.05",
+				"RokkuSTSUnreachable":             "> 0",
+				"RokkuRangerPolicyRefreshFailing": "> 600",
+			},
+		},
+		{
+			name: "overrides",
+			alerts: v1alpha1.RokkuAlerts{
+				HighErrorRate:              &v1alpha1.RokkuAlert{Threshold: "0.1"},
+				STSUnreachable:             &v1alpha1.RokkuAlert{Disabled: true},
+				RangerPolicyRefreshFailing: &v1alpha1.RokkuAlert{Threshold: "1e3"},
+			},
+			wantRules: map[string]string{
+				"RokkuPodsNotReady":               "> 0",
+				"RokkuHighErrorRate":              "> 0.1",
+				"RokkuRangerPolicyRefreshFailing": "> 1e3",
+			},
+		},
+		{
+			name: "threshold injecting PromQL",
+			alerts: v1alpha1.RokkuAlerts{
+				PodsNotReady: &v1alpha1.RokkuAlert{Threshold: "0 or vector(1)"},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rokku := newTestRokku()
+			alerts := tt.alerts
+			rokku.Spec.Monitoring = &v1alpha1.RokkuMonitoring{Alerts: &alerts}
+
+			rule, err := NewPrometheusRule(rokku)
+			if tt.wantErr {
+				if _, ok := err.(*ValidationError); !ok {
+					t.Fatalf("expected a ValidationError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			rules := rule.Spec.Groups[0].Rules
+			if len(rules) != len(tt.wantRules) {
+				t.Fatalf("got %d rules, want %d", len(rules), len(tt.wantRules))
+			}
+			for _, r := range rules {
+				suffix, found := tt.wantRules[r.Alert]
+				if !found {
+					t.Errorf("unexpected alert %s", r.Alert)
+					continue
+				}
+				if expr := r.Expr.String(); !strings.HasSuffix(expr, suffix) {
+					t.Errorf("alert %s expression %q must end with %q", r.Alert, expr, suffix)
+				}
+				if r.Labels["rokku_name"] != "rokku" || r.Labels["rokku_namespace"] != "default" {
+					t.Errorf("alert %s has labels %v", r.Alert, r.Labels)
+				}
+			}
+		})
+	}
+}
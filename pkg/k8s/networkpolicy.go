@@ -0,0 +1,133 @@
+package k8s
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// NetworkPolicyName returns the name of the NetworkPolicy for the given Rokku.
+func NetworkPolicyName(name string) string {
+	return name + "-network-policy"
+}
+
+// NewNetworkPolicy returns the NetworkPolicy restricting which peers can reach
+// Rokku and which backends Rokku can reach.
+func NewNetworkPolicy(n *v1alpha1.Rokku) *networkingv1.NetworkPolicy {
+	np := &networkingv1.NetworkPolicy{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "NetworkPolicy",
+			APIVersion: "networking.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            NetworkPolicyName(n.Name),
+			Namespace:       n.Namespace,
+			OwnerReferences: ownerReferences(n),
+			Labels:          LabelsForRokku(n.Name),
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: LabelsForRokku(n.Name),
+			},
+			PolicyTypes: []networkingv1.PolicyType{
+				networkingv1.PolicyTypeIngress,
+				networkingv1.PolicyTypeEgress,
+			},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{
+				{
+					Ports: []networkingv1.NetworkPolicyPort{
+						tcpPolicyPort(intstr.FromString(defaultHTTPPortName)),
+						tcpPolicyPort(intstr.FromString(defaultHTTPSPortName)),
+					},
+				},
+			},
+			Egress: backendEgressRules(n.Spec),
+		},
+	}
+	if n.Spec.Monitoring != nil {
+		np.Spec.Ingress = append(np.Spec.Ingress, networkingv1.NetworkPolicyIngressRule{
+			Ports: []networkingv1.NetworkPolicyPort{
+				tcpPolicyPort(intstr.FromString(defaultMetricsPortName)),
+			},
+		})
+	}
+	if n.Spec.NetworkPolicy != nil {
+		np.Spec.Ingress[0].From = n.Spec.NetworkPolicy.AllowedClients
+		if n.Spec.Monitoring != nil {
+			np.Spec.Ingress[1].From = n.Spec.NetworkPolicy.MetricsClients
+		}
+		np.Spec.Egress = append(np.Spec.Egress, n.Spec.NetworkPolicy.ExtraEgress...)
+	}
+	return np
+}
+
+// backendEgressRules derives one egress rule per backend endpoint. Since
+// NetworkPolicies can't match on DNS names, endpoints given as IP addresses are
+// restricted to that address while hostnames only restrict the port.
+func backendEgressRules(spec v1alpha1.RokkuSpec) []networkingv1.NetworkPolicyEgressRule {
+	dnsPort := intstr.FromInt(53)
+	udp := corev1.ProtocolUDP
+	rules := []networkingv1.NetworkPolicyEgressRule{
+		{
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: &udp, Port: &dnsPort},
+				tcpPolicyPort(dnsPort),
+			},
+		},
+	}
+
+	s3Host, s3Port := s3Backend(spec)
+	endpoints := []string{net.JoinHostPort(s3Host, s3Port)}
+	if host, port, ok := splitURL(stsBackend(spec)); ok {
+		endpoints = append(endpoints, net.JoinHostPort(host, port))
+	}
+	if spec.Backends != nil {
+		for _, u := range []string{spec.Backends.RangerAdmin, spec.Backends.Atlas} {
+			if host, port, ok := splitURL(u); ok {
+				endpoints = append(endpoints, net.JoinHostPort(host, port))
+			}
+		}
+		endpoints = append(endpoints, spec.Backends.Kafka...)
+	}
+	if spec.Proxy != nil {
+		for _, u := range []string{spec.Proxy.HTTPProxy, spec.Proxy.HTTPSProxy} {
+			if host, port, ok := splitURL(u); ok {
+				endpoints = append(endpoints, net.JoinHostPort(host, port))
+			}
+		}
+	}
+
+	for _, endpoint := range endpoints {
+		host, portStr, err := net.SplitHostPort(strings.TrimSpace(endpoint))
+		if err != nil {
+			continue
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			continue
+		}
+		rule := networkingv1.NetworkPolicyEgressRule{
+			Ports: []networkingv1.NetworkPolicyPort{tcpPolicyPort(intstr.FromInt(port))},
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			cidr := ip.String() + "/32"
+			if ip.To4() == nil {
+				cidr = ip.String() + "/128"
+			}
+			rule.To = []networkingv1.NetworkPolicyPeer{{IPBlock: &networkingv1.IPBlock{CIDR: cidr}}}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+func tcpPolicyPort(port intstr.IntOrString) networkingv1.NetworkPolicyPort {
+	tcp := corev1.ProtocolTCP
+	return networkingv1.NetworkPolicyPort{Protocol: &tcp, Port: &port}
+}
@@ -0,0 +1,56 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewNetworkPolicy(t *testing.T) {
+	rokku := newTestRokku()
+	rokku.Spec.Backends = &v1alpha1.RokkuBackends{
+		S3:          "http://10.0.0.5:7480",
+		STS:         "http://rokku-sts:8080",
+		RangerAdmin: "https://ranger.example.com",
+		Kafka:       []string{"kafka-0:9092", "not-an-endpoint"},
+	}
+	rokku.Spec.Monitoring = &v1alpha1.RokkuMonitoring{}
+	clients := []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "data"}}}}
+	prometheus := []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "prometheus"}}}}
+	rokku.Spec.NetworkPolicy = &v1alpha1.RokkuNetworkPolicy{AllowedClients: clients, MetricsClients: prometheus}
+
+	np := NewNetworkPolicy(rokku)
+
+	if len(np.Spec.Ingress) != 2 {
+		t.Fatalf("got %d ingress rules, want the Rokku and the metrics ones", len(np.Spec.Ingress))
+	}
+	if !reflect.DeepEqual(np.Spec.Ingress[0].From, clients) || len(np.Spec.Ingress[0].Ports) != 2 {
+		t.Errorf("rokku ingress rule = %+v, want the http and https ports from the allowed clients", np.Spec.Ingress[0])
+	}
+	if rule := np.Spec.Ingress[1]; !reflect.DeepEqual(rule.From, prometheus) || rule.Ports[0].Port.StrVal != defaultMetricsPortName {
+		t.Errorf("metrics ingress rule = %+v, want the metrics port from the metrics clients", rule)
+	}
+
+	type egress struct {
+		port int
+		cidr string
+	}
+	var got []egress
+	for _, rule := range np.Spec.Egress[1:] {
+		e := egress{port: rule.Ports[0].Port.IntValue()}
+		if len(rule.To) > 0 {
+			e.cidr = rule.To[0].IPBlock.CIDR
+		}
+		got = append(got, e)
+	}
+	want := []egress{{port: 7480, cidr: "10.0.0.5/32"}, {port: 8080}, {port: 443}, {port: 9092}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("backend egress rules = %+v, want %+v", got, want)
+	}
+	if dns := np.Spec.Egress[0]; len(dns.Ports) != 2 || dns.Ports[0].Port.IntValue() != 53 {
+		t.Errorf("first egress rule = %+v, want DNS", dns)
+	}
+}
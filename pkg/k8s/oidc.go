@@ -0,0 +1,74 @@
+package k8s
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const realmsPathPrefix = "/realms/"
+
+// OIDCDiscoveryURL returns the URL of the discovery document of the issuer.
+func OIDCDiscoveryURL(oidc *v1alpha1.RokkuOIDC) string {
+	return strings.TrimSuffix(oidc.IssuerURL, "/") + "/.well-known/openid-configuration"
+}
+
+// oidcRealm returns the realm of the issuer, e.g. auth-rokku for
+// https://keycloak:8443/auth/realms/auth-rokku.
+func oidcRealm(oidc *v1alpha1.RokkuOIDC) string {
+	if oidc.Realm != "" {
+		return oidc.Realm
+	}
+	issuer := strings.TrimSuffix(oidc.IssuerURL, "/")
+	if i := strings.LastIndex(issuer, realmsPathPrefix); i >= 0 {
+		return issuer[i+len(realmsPathPrefix):]
+	}
+	return ""
+}
+
+// keycloakURL returns the Keycloak base URL of the issuer, which rokku-sts
+// appends the realm path to.
+func keycloakURL(oidc *v1alpha1.RokkuOIDC) string {
+	issuer := strings.TrimSuffix(oidc.IssuerURL, "/")
+	if i := strings.LastIndex(issuer, realmsPathPrefix); i >= 0 {
+		issuer = issuer[:i]
+	}
+	return strings.TrimSuffix(issuer, "/auth")
+}
+
+func oidcEnv(oidc *v1alpha1.RokkuOIDC) []corev1.EnvVar {
+	if oidc == nil {
+		return nil
+	}
+
+	env := []corev1.EnvVar{
+		{Name: "KEYCLOAK_URL", Value: keycloakURL(oidc)},
+		{Name: "KEYCLOAK_REALM", Value: oidcRealm(oidc)},
+	}
+	if oidc.ClientID != "" {
+		env = append(env, corev1.EnvVar{Name: "KEYCLOAK_RESOURCE", Value: oidc.ClientID})
+	}
+	if oidc.ClientSecret != nil {
+		env = append(env, corev1.EnvVar{
+			Name:      "KEYCLOAK_CLIENT_SECRET",
+			ValueFrom: &corev1.EnvVarSource{SecretKeyRef: oidc.ClientSecret.DeepCopy()},
+		})
+	}
+
+	verify := oidc.VerifyToken
+	if verify == nil {
+		return env
+	}
+	if verify.PublicKeyID != "" {
+		env = append(env, corev1.EnvVar{Name: "KEYCLOAK_PUBLIC_KEY_ID", Value: verify.PublicKeyID})
+	}
+	if verify.CheckRealmURL != nil {
+		env = append(env, corev1.EnvVar{Name: "KEYCLOAK_CHECK_REALM_URL", Value: strconv.FormatBool(*verify.CheckRealmURL)})
+	}
+	if len(verify.IssuerForList) > 0 {
+		env = append(env, corev1.EnvVar{Name: "KEYCLOAK_CHECK_ISSUER_FOR_LIST", Value: strings.Join(verify.IssuerForList, ",")})
+	}
+	return env
+}
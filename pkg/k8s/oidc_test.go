@@ -0,0 +1,118 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestOIDCIssuer(t *testing.T) {
+	tests := []struct {
+		name          string
+		oidc          v1alpha1.RokkuOIDC
+		wantDiscovery string
+		wantRealm     string
+		wantKeycloak  string
+	}{
+		{
+			name:          "legacy keycloak",
+			oidc:          v1alpha1.RokkuOIDC{IssuerURL: "https://keycloak:8443/auth/realms/auth-rokku"},
+			wantDiscovery: "https://keycloak:8443/auth/realms/auth-rokku/.well-known/openid-configuration",
+			wantRealm:     "auth-rokku",
+			wantKeycloak:  "https://keycloak:8443",
+		},
+		{
+			name:          "trailing slash",
+			oidc:          v1alpha1.RokkuOIDC{IssuerURL: "https://keycloak/realms/rokku/"},
+			wantDiscovery: "https://keycloak/realms/rokku/.well-known/openid-configuration",
+			wantRealm:     "rokku",
+			wantKeycloak:  "https://keycloak",
+		},
+		{
+			name:          "explicit realm",
+			oidc:          v1alpha1.RokkuOIDC{IssuerURL: "https://sso.example.com/realms/rokku", Realm: "other"},
+			wantDiscovery: "https://sso.example.com/realms/rokku/.well-known/openid-configuration",
+			wantRealm:     "other",
+			wantKeycloak:  "https://sso.example.com",
+		},
+		{
+			name:          "not keycloak",
+			oidc:          v1alpha1.RokkuOIDC{IssuerURL: "https://issuer.example.com"},
+			wantDiscovery: "https://issuer.example.com/.well-known/openid-configuration",
+			wantRealm:     "",
+			wantKeycloak:  "https://issuer.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OIDCDiscoveryURL(&tt.oidc); got != tt.wantDiscovery {
+				t.Errorf("OIDCDiscoveryURL() = %q, want %q", got, tt.wantDiscovery)
+			}
+			if got := oidcRealm(&tt.oidc); got != tt.wantRealm {
+				t.Errorf("oidcRealm() = %q, want %q", got, tt.wantRealm)
+			}
+			if got := keycloakURL(&tt.oidc); got != tt.wantKeycloak {
+				t.Errorf("keycloakURL() = %q, want %q", got, tt.wantKeycloak)
+			}
+		})
+	}
+}
+
+func TestOIDCEnv(t *testing.T) {
+	yes := true
+	secret := &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "keycloak"},
+		Key:                  "secret",
+	}
+
+	tests := []struct {
+		name string
+		oidc *v1alpha1.RokkuOIDC
+		want []corev1.EnvVar
+	}{
+		{
+			name: "no oidc",
+		},
+		{
+			name: "issuer only",
+			oidc: &v1alpha1.RokkuOIDC{IssuerURL: "https://keycloak/auth/realms/rokku"},
+			want: []corev1.EnvVar{
+				{Name: "KEYCLOAK_URL", Value: "https://keycloak"},
+				{Name: "KEYCLOAK_REALM", Value: "rokku"},
+			},
+		},
+		{
+			name: "client and verification",
+			oidc: &v1alpha1.RokkuOIDC{
+				IssuerURL:    "https://keycloak/auth/realms/rokku",
+				ClientID:     "sts-rokku",
+				ClientSecret: secret,
+				VerifyToken: &v1alpha1.RokkuOIDCVerifyToken{
+					PublicKeyID:   "key-1",
+					CheckRealmURL: &yes,
+					IssuerForList: []string{"sts-rokku", "account"},
+				},
+			},
+			want: []corev1.EnvVar{
+				{Name: "KEYCLOAK_URL", Value: "https://keycloak"},
+				{Name: "KEYCLOAK_REALM", Value: "rokku"},
+				{Name: "KEYCLOAK_RESOURCE", Value: "sts-rokku"},
+				{Name: "KEYCLOAK_CLIENT_SECRET", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: secret}},
+				{Name: "KEYCLOAK_PUBLIC_KEY_ID", Value: "key-1"},
+				{Name: "KEYCLOAK_CHECK_REALM_URL", Value: "true"},
+				{Name: "KEYCLOAK_CHECK_ISSUER_FOR_LIST", Value: "sts-rokku,account"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := oidcEnv(tt.oidc); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("oidcEnv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
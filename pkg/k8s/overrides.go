@@ -0,0 +1,89 @@
+package k8s
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+)
+
+// applyDeploymentOverrides applies spec.overrides.deployment to the generated
+// Deployment.
+func applyDeploymentOverrides(overrides *v1alpha1.RokkuOverrides, dep *appv1.Deployment) error {
+	if overrides == nil || overrides.Deployment == nil {
+		return nil
+	}
+
+	patched := &appv1.Deployment{}
+	if err := strategicMergePatch(dep, overrides.Deployment, patched); err != nil {
+		return validationErrorf("invalid deployment overrides: %v", err)
+	}
+
+	if msg := validateOverriddenMeta(dep.ObjectMeta, patched.ObjectMeta); msg != "" {
+		return validationErrorf("invalid deployment overrides: %s", msg)
+	}
+	if !reflect.DeepEqual(dep.Spec.Selector, patched.Spec.Selector) {
+		return validationErrorf("invalid deployment overrides: selector can't be changed")
+	}
+	selector, err := metav1.LabelSelectorAsSelector(patched.Spec.Selector)
+	if err != nil {
+		return validationErrorf("invalid deployment overrides: %v", err)
+	}
+	if !selector.Matches(labels.Set(patched.Spec.Template.Labels)) {
+		return validationErrorf("invalid deployment overrides: pod labels must match the selector")
+	}
+
+	*dep = *patched
+	return nil
+}
+
+// applyServiceOverrides applies spec.overrides.service to the generated
+// Service.
+func applyServiceOverrides(overrides *v1alpha1.RokkuOverrides, svc *corev1.Service) error {
+	if overrides == nil || overrides.Service == nil {
+		return nil
+	}
+
+	patched := &corev1.Service{}
+	if err := strategicMergePatch(svc, overrides.Service, patched); err != nil {
+		return validationErrorf("invalid service overrides: %v", err)
+	}
+
+	if msg := validateOverriddenMeta(svc.ObjectMeta, patched.ObjectMeta); msg != "" {
+		return validationErrorf("invalid service overrides: %s", msg)
+	}
+	if !reflect.DeepEqual(svc.Spec.Selector, patched.Spec.Selector) {
+		return validationErrorf("invalid service overrides: selector can't be changed")
+	}
+
+	*svc = *patched
+	return nil
+}
+
+func strategicMergePatch(original runtime.Object, patch *runtime.RawExtension, patched runtime.Object) error {
+	originalJSON, err := json.Marshal(original)
+	if err != nil {
+		return err
+	}
+	patchedJSON, err := strategicpatch.StrategicMergePatch(originalJSON, patch.Raw, patched)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(patchedJSON, patched)
+}
+
+func validateOverriddenMeta(original, patched metav1.ObjectMeta) string {
+	if original.Name != patched.Name || original.Namespace != patched.Namespace {
+		return "name and namespace can't be changed"
+	}
+	if !reflect.DeepEqual(original.OwnerReferences, patched.OwnerReferences) {
+		return "owner references can't be changed"
+	}
+	return ""
+}
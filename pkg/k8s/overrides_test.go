@@ -0,0 +1,150 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestApplyDeploymentOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides *v1alpha1.RokkuOverrides
+		check     func(t *testing.T, rokku *v1alpha1.Rokku)
+		wantErr   bool
+	}{
+		{
+			name: "no overrides",
+		},
+		{
+			name: "adds fields",
+			overrides: &v1alpha1.RokkuOverrides{Deployment: &runtime.RawExtension{
+				Raw: []byte(`{"spec":{"minReadySeconds":10,"template":{"spec":{"priorityClassName":"high"}}}}`),
+			}},
+			check: func(t *testing.T, rokku *v1alpha1.Rokku) {
+				dep, err := NewDeployment(rokku)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if dep.Spec.MinReadySeconds != 10 {
+					t.Errorf("minReadySeconds = %d, want 10", dep.Spec.MinReadySeconds)
+				}
+				if dep.Spec.Template.Spec.PriorityClassName != "high" {
+					t.Errorf("priorityClassName = %q, want high", dep.Spec.Template.Spec.PriorityClassName)
+				}
+				if len(dep.Spec.Template.Spec.Containers) != 1 {
+					t.Errorf("got %d containers, want the rokku container kept", len(dep.Spec.Template.Spec.Containers))
+				}
+			},
+		},
+		{
+			name: "changes the selector",
+			overrides: &v1alpha1.RokkuOverrides{Deployment: &runtime.RawExtension{
+				Raw: []byte(`{"spec":{"selector":{"matchLabels":{"rokku.ing.com/app":"other"}}}}`),
+			}},
+			wantErr: true,
+		},
+		{
+			name: "changes the pod labels",
+			overrides: &v1alpha1.RokkuOverrides{Deployment: &runtime.RawExtension{
+				Raw: []byte(`{"spec":{"template":{"metadata":{"labels":{"rokku.ing.com/app":"other"}}}}}`),
+			}},
+			wantErr: true,
+		},
+		{
+			name: "changes the name",
+			overrides: &v1alpha1.RokkuOverrides{Deployment: &runtime.RawExtension{
+				Raw: []byte(`{"metadata":{"name":"other"}}`),
+			}},
+			wantErr: true,
+		},
+		{
+			name: "invalid patch",
+			overrides: &v1alpha1.RokkuOverrides{Deployment: &runtime.RawExtension{
+				Raw: []byte(`{"spec":`),
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rokku := newTestRokku()
+			rokku.Spec.Overrides = tt.overrides
+
+			_, err := NewDeployment(rokku)
+			if tt.wantErr {
+				if _, ok := err.(*ValidationError); !ok {
+					t.Fatalf("NewDeployment() = %v, want a ValidationError", err)
+				}
+				if _, ok := ValidateRokku(rokku).(*ValidationError); !ok {
+					t.Errorf("expected ValidateRokku() to reject the overrides")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.check != nil {
+				tt.check(t, rokku)
+			}
+		})
+	}
+}
+
+func TestApplyServiceOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides *v1alpha1.RokkuOverrides
+		wantErr   bool
+	}{
+		{
+			name: "no overrides",
+		},
+		{
+			name: "adds fields",
+			overrides: &v1alpha1.RokkuOverrides{Service: &runtime.RawExtension{
+				Raw: []byte(`{"spec":{"externalTrafficPolicy":"Local"}}`),
+			}},
+		},
+		{
+			name: "changes the selector",
+			overrides: &v1alpha1.RokkuOverrides{Service: &runtime.RawExtension{
+				Raw: []byte(`{"spec":{"selector":{"rokku.ing.com/app":"other"}}}`),
+			}},
+			wantErr: true,
+		},
+		{
+			name: "changes the namespace",
+			overrides: &v1alpha1.RokkuOverrides{Service: &runtime.RawExtension{
+				Raw: []byte(`{"metadata":{"namespace":"other"}}`),
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rokku := newTestRokku()
+			rokku.Spec.Overrides = tt.overrides
+
+			svc, err := NewService(rokku)
+			if tt.wantErr {
+				if _, ok := err.(*ValidationError); !ok {
+					t.Fatalf("NewService() = %v, want a ValidationError", err)
+				}
+				if _, ok := ValidateRokku(rokku).(*ValidationError); !ok {
+					t.Errorf("expected ValidateRokku() to reject the overrides")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.overrides != nil && svc.Spec.ExternalTrafficPolicy != "Local" {
+				t.Errorf("externalTrafficPolicy = %q, want Local", svc.Spec.ExternalTrafficPolicy)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package k8s
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	"golang.org/x/net/http/httpproxy"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// noProxy returns the hosts reached without the proxy, including the cluster
+// services and the S3 backend, which must never go through it.
+func noProxy(spec v1alpha1.RokkuSpec) []string {
+	clusterDomain := valueOrDefault(os.Getenv("CLUSTER_DOMAIN"), "cluster.local")
+	s3Host, _ := s3Backend(spec)
+	hosts := []string{"localhost", "127.0.0.1", ".svc", "." + clusterDomain, s3Host}
+	if cidr := serviceCIDR(spec.Proxy); cidr != "" {
+		hosts = append(hosts, cidr)
+	}
+
+	seen := make(map[string]bool)
+	var result []string
+	for _, h := range append(hosts, spec.Proxy.NoProxy...) {
+		if h == "" || seen[h] {
+			continue
+		}
+		seen[h] = true
+		result = append(result, h)
+	}
+	return result
+}
+
+// serviceCIDR returns the CIDR of the cluster services, as set in the spec or
+// else in the SERVICE_CIDR environment variable of the operator. Otherwise it
+// guesses the /16 holding the ClusterIP of the kubernetes Service, which the
+// operator pod gets through the KUBERNETES_SERVICE_HOST environment variable.
+// That's only a heuristic: a larger service CIDR is partly reached through
+// the proxy, and a smaller one exempts other hosts from it.
+func serviceCIDR(proxy *v1alpha1.RokkuProxy) string {
+	if cidr := valueOrDefault(proxy.ServiceCIDR, os.Getenv("SERVICE_CIDR")); cidr != "" {
+		return cidr
+	}
+	ip := net.ParseIP(os.Getenv("KUBERNETES_SERVICE_HOST")).To4()
+	if ip == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d.%d.0.0/16", ip[0], ip[1])
+}
+
+// cidrWildcards renders an IPv4 CIDR into the wildcard patterns understood by
+// the JVM, e.g. 10.96.0.0/12 into 10.96.*, 10.97.*, ..., 10.111.*. Other
+// CIDRs can't be rendered and nil is returned.
+func cidrWildcards(cidr string) []string {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil
+	}
+	ip := network.IP.To4()
+	ones, bits := network.Mask.Size()
+	if ip == nil || bits != 32 || ones == 0 {
+		return nil
+	}
+
+	full, partial := ones/8, ones%8
+	if full == 4 {
+		return []string{ip.String()}
+	}
+	prefix := ""
+	for _, b := range ip[:full] {
+		prefix += strconv.Itoa(int(b)) + "."
+	}
+	if partial == 0 {
+		return []string{prefix + "*"}
+	}
+	var wildcards []string
+	first := int(ip[full])
+	for b := first; b < first+1<<(8-partial); b++ {
+		wildcard := prefix + strconv.Itoa(b)
+		if full < 3 {
+			wildcard += ".*"
+		}
+		wildcards = append(wildcards, wildcard)
+	}
+	return wildcards
+}
+
+// proxyEnv renders the proxy settings into the environment variables honored
+// by most tools, in both their upper and lower case forms.
+func proxyEnv(spec v1alpha1.RokkuSpec) []corev1.EnvVar {
+	if spec.Proxy == nil {
+		return nil
+	}
+
+	var env []corev1.EnvVar
+	add := func(name, value string) {
+		if value == "" {
+			return
+		}
+		env = append(env,
+			corev1.EnvVar{Name: name, Value: value},
+			corev1.EnvVar{Name: strings.ToLower(name), Value: value},
+		)
+	}
+	add("HTTP_PROXY", spec.Proxy.HTTPProxy)
+	add("HTTPS_PROXY", spec.Proxy.HTTPSProxy)
+	add("NO_PROXY", strings.Join(noProxy(spec), ","))
+	return env
+}
+
+// ProxyFunc returns the function choosing the proxy of the requests sent on
+// behalf of the given Rokku, following its proxy settings. It returns nil when
+// no proxy is configured.
+func ProxyFunc(spec v1alpha1.RokkuSpec) func(*url.URL) (*url.URL, error) {
+	if spec.Proxy == nil {
+		return nil
+	}
+	config := httpproxy.Config{
+		HTTPProxy:  spec.Proxy.HTTPProxy,
+		HTTPSProxy: spec.Proxy.HTTPSProxy,
+		NoProxy:    strings.Join(noProxy(spec), ","),
+	}
+	return config.ProxyFunc()
+}
+
+// proxyJavaOpts renders the proxy settings into the JVM proxy properties.
+func proxyJavaOpts(spec v1alpha1.RokkuSpec) []string {
+	if spec.Proxy == nil {
+		return nil
+	}
+
+	var opts []string
+	if host, port, ok := splitURL(spec.Proxy.HTTPProxy); ok {
+		opts = append(opts, "-Dhttp.proxyHost="+host, "-Dhttp.proxyPort="+port)
+	}
+	if host, port, ok := splitURL(spec.Proxy.HTTPSProxy); ok {
+		opts = append(opts, "-Dhttps.proxyHost="+host, "-Dhttps.proxyPort="+port)
+	}
+	if len(opts) == 0 {
+		return nil
+	}
+
+	// The JVM matches the hosts with wildcards only, and applies
+	// http.nonProxyHosts to HTTPS as well
+	var nonProxyHosts []string
+	for _, h := range noProxy(spec) {
+		if _, _, err := net.ParseCIDR(h); err == nil {
+			nonProxyHosts = append(nonProxyHosts, cidrWildcards(h)...)
+			continue
+		}
+		if strings.HasPrefix(h, ".") {
+			h = "*" + h
+		}
+		nonProxyHosts = append(nonProxyHosts, h)
+	}
+	return append(opts, "-Dhttp.nonProxyHosts="+strings.Join(nonProxyHosts, "|"))
+}
@@ -0,0 +1,175 @@
+package k8s
+
+import (
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+)
+
+func TestCIDRWildcards(t *testing.T) {
+	var serviceRange []string
+	for b := 96; b < 112; b++ {
+		serviceRange = append(serviceRange, "10."+strconv.Itoa(b)+".*")
+	}
+
+	tests := []struct {
+		cidr string
+		want []string
+	}{
+		{cidr: "10.0.0.0/8", want: []string{"10.*"}},
+		{cidr: "172.30.0.0/16", want: []string{"172.30.*"}},
+		{cidr: "192.168.1.0/24", want: []string{"192.168.1.*"}},
+		{cidr: "192.168.1.10/32", want: []string{"192.168.1.10"}},
+		{cidr: "10.96.0.0/14", want: []string{"10.96.*", "10.97.*", "10.98.*", "10.99.*"}},
+		{cidr: "10.96.4.0/23", want: []string{"10.96.4.*", "10.96.5.*"}},
+		{cidr: "192.168.1.8/30", want: []string{"192.168.1.8", "192.168.1.9", "192.168.1.10", "192.168.1.11"}},
+		{cidr: "10.97.0.0/12", want: serviceRange},
+		{cidr: "fd00::/108", want: nil},
+		{cidr: "0.0.0.0/0", want: nil},
+		{cidr: "not-a-cidr", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cidr, func(t *testing.T) {
+			if got := cidrWildcards(tt.cidr); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cidrWildcards(%q) = %v, want %v", tt.cidr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceCIDR(t *testing.T) {
+	tests := []struct {
+		name        string
+		spec        string
+		env         string
+		serviceHost string
+		want        string
+	}{
+		{name: "spec", spec: "10.100.0.0/16", env: "10.0.0.0/8", serviceHost: "10.96.0.1", want: "10.100.0.0/16"},
+		{name: "environment", env: "10.0.0.0/8", serviceHost: "10.96.0.1", want: "10.0.0.0/8"},
+		{name: "kubernetes service", serviceHost: "10.96.0.1", want: "10.96.0.0/16"},
+		{name: "ipv6 kubernetes service", serviceHost: "fd00::1", want: ""},
+		{name: "unknown", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer setenv("SERVICE_CIDR", tt.env)()
+			defer setenv("KUBERNETES_SERVICE_HOST", tt.serviceHost)()
+			if got := serviceCIDR(&v1alpha1.RokkuProxy{ServiceCIDR: tt.spec}); got != tt.want {
+				t.Errorf("serviceCIDR() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxySettings(t *testing.T) {
+	defer setenv("SERVICE_CIDR", "10.96.0.0/15")()
+	defer setenv("CLUSTER_DOMAIN", "")()
+
+	spec := v1alpha1.RokkuSpec{
+		Backends: &v1alpha1.RokkuBackends{S3: "http://ceph:7480"},
+		Proxy: &v1alpha1.RokkuProxy{
+			HTTPProxy:  "http://proxy:3128",
+			HTTPSProxy: "http://proxy:3129",
+			NoProxy:    []string{".internal", "ceph", "192.168.0.0/24"},
+		},
+	}
+
+	wantNoProxy := []string{"localhost", "127.0.0.1", ".svc", ".cluster.local", "ceph", "10.96.0.0/15", ".internal", "192.168.0.0/24"}
+	if got := noProxy(spec); !reflect.DeepEqual(got, wantNoProxy) {
+		t.Errorf("noProxy() = %v, want %v", got, wantNoProxy)
+	}
+
+	env := make(map[string]string)
+	for _, e := range proxyEnv(spec) {
+		env[e.Name] = e.Value
+	}
+	for _, name := range []string{"HTTP_PROXY", "http_proxy"} {
+		if env[name] != "http://proxy:3128" {
+			t.Errorf("%s = %q, want http://proxy:3128", name, env[name])
+		}
+	}
+	if want := strings.Join(wantNoProxy, ","); env["NO_PROXY"] != want {
+		t.Errorf("NO_PROXY = %q, want %q", env["NO_PROXY"], want)
+	}
+
+	wantOpts := []string{
+		"-Dhttp.proxyHost=proxy", "-Dhttp.proxyPort=3128",
+		"-Dhttps.proxyHost=proxy", "-Dhttps.proxyPort=3129",
+		"-Dhttp.nonProxyHosts=localhost|127.0.0.1|*.svc|*.cluster.local|ceph|10.96.*|10.97.*|*.internal|192.168.0.*",
+	}
+	if got := proxyJavaOpts(spec); !reflect.DeepEqual(got, wantOpts) {
+		t.Errorf("proxyJavaOpts() = %v, want %v", got, wantOpts)
+	}
+
+	proxy := ProxyFunc(spec)
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://keycloak.example.com/auth", want: "http://proxy:3129"},
+		{url: "http://keycloak.example.com/auth", want: "http://proxy:3128"},
+		{url: "https://keycloak.internal/auth", want: ""},
+		{url: "http://rokku-sts.default.svc:8080", want: ""},
+		{url: "http://10.97.1.2:8080", want: ""},
+	}
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		got, err := proxy(u)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got == nil && tt.want != "" || got != nil && got.String() != tt.want {
+			t.Errorf("proxy for %s = %v, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestNoProxySettings(t *testing.T) {
+	spec := v1alpha1.RokkuSpec{}
+	if env := proxyEnv(spec); env != nil {
+		t.Errorf("proxyEnv() = %v, want nil", env)
+	}
+	if opts := proxyJavaOpts(spec); opts != nil {
+		t.Errorf("proxyJavaOpts() = %v, want nil", opts)
+	}
+	if proxy := ProxyFunc(spec); proxy != nil {
+		t.Error("ProxyFunc() must be nil without proxy settings")
+	}
+}
+
+// setenv sets the environment variable, unsetting it when value is empty,
+// and returns the function restoring its previous value.
+func setenv(key, value string) func() {
+	previous, found := os.LookupEnv(key)
+	if value == "" {
+		os.Unsetenv(key)
+	} else {
+		os.Setenv(key, value)
+	}
+	return func() {
+		if found {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func TestValidateServiceCIDR(t *testing.T) {
+	for cidr, wantErr := range map[string]bool{"": false, "10.96.0.0/12": false, "10.96.0.1": true} {
+		rokku := newTestRokku()
+		rokku.Spec.Proxy = &v1alpha1.RokkuProxy{HTTPProxy: "http://proxy:3128", ServiceCIDR: cidr}
+		err := ValidateRokku(rokku)
+		if _, ok := err.(*ValidationError); ok != wantErr {
+			t.Errorf("ValidateRokku() with serviceCIDR %q = %v, want a ValidationError: %v", cidr, err, wantErr)
+		}
+	}
+}
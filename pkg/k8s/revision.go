@@ -0,0 +1,63 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	appv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// revisionOfLabel tells the Rokku a ControllerRevision stores a spec of. It
+// keeps them apart from the ControllerRevisions of the Rokku DaemonSet, which
+// carry the pod labels.
+const revisionOfLabel = "rokku.ing.com/revision-of"
+
+// LabelsForRevisions returns the labels of the ControllerRevisions of the
+// Rokku with the given name.
+func LabelsForRevisions(name string) map[string]string {
+	return map[string]string{revisionOfLabel: name}
+}
+
+// RevisionName returns the name of the ControllerRevision holding the spec
+// with the given hash.
+func RevisionName(name, hash string) string {
+	return fmt.Sprintf("%s-%s", name, hash)
+}
+
+// NewControllerRevision returns the ControllerRevision storing the given spec
+// of the given Rokku as its revision number revision. The spec is stored as
+// submitted, before its defaults are set.
+func NewControllerRevision(n *v1alpha1.Rokku, spec v1alpha1.RokkuSpec, hash string, revision int64) (*appv1.ControllerRevision, error) {
+	spec.RollbackTo = nil
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	return &appv1.ControllerRevision{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ControllerRevision",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            RevisionName(n.Name, hash),
+			Namespace:       n.Namespace,
+			OwnerReferences: ownerReferences(n),
+			Labels:          LabelsForRevisions(n.Name),
+		},
+		Data:     runtime.RawExtension{Raw: data},
+		Revision: revision,
+	}, nil
+}
+
+// ExtractRevisionSpec returns the Rokku spec stored in the given
+// ControllerRevision.
+func ExtractRevisionSpec(rev *appv1.ControllerRevision) (v1alpha1.RokkuSpec, error) {
+	var spec v1alpha1.RokkuSpec
+	if err := json.Unmarshal(rev.Data.Raw, &spec); err != nil {
+		return v1alpha1.RokkuSpec{}, fmt.Errorf("failed to unmarshal rokku from revision %s: %v", rev.Name, err)
+	}
+	return spec, nil
+}
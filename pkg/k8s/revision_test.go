@@ -0,0 +1,77 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+)
+
+func TestControllerRevision(t *testing.T) {
+	rokku := newTestRokku()
+	rokku.UID = "rokku-uid"
+	revision := int64(2)
+	spec := v1alpha1.RokkuSpec{Image: "wbaa/rokku:1", RollbackTo: &revision}
+
+	rev, err := NewControllerRevision(rokku, spec, "0123456789abcdef", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rev.Name != "rokku-0123456789abcdef" || rev.Revision != 3 {
+		t.Errorf("revision = %s (%d), want rokku-0123456789abcdef (3)", rev.Name, rev.Revision)
+	}
+	if want := LabelsForRevisions("rokku"); !reflect.DeepEqual(rev.Labels, want) {
+		t.Errorf("labels = %v, want %v", rev.Labels, want)
+	}
+	if len(rev.OwnerReferences) != 1 || rev.OwnerReferences[0].UID != rokku.UID {
+		t.Errorf("owner references = %v, want the Rokku", rev.OwnerReferences)
+	}
+
+	got, err := ExtractRevisionSpec(rev)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := v1alpha1.RokkuSpec{Image: "wbaa/rokku:1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stored spec = %+v, want %+v", got, want)
+	}
+	if spec.RollbackTo == nil {
+		t.Error("the given spec must not be mutated")
+	}
+}
+
+func TestSpecHash(t *testing.T) {
+	one, two := int32(1), int32(2)
+	revision := int64(3)
+	base := v1alpha1.RokkuSpec{Image: "wbaa/rokku:1", Replicas: &one}
+
+	tests := []struct {
+		name   string
+		mutate func(*v1alpha1.RokkuSpec)
+		same   bool
+	}{
+		{name: "replicas", mutate: func(s *v1alpha1.RokkuSpec) { s.Replicas = &two }, same: true},
+		{name: "maintenance", mutate: func(s *v1alpha1.RokkuSpec) { s.Maintenance = &v1alpha1.RokkuMaintenance{Enabled: true} }, same: true},
+		{name: "revision history", mutate: func(s *v1alpha1.RokkuSpec) { s.RevisionHistoryLimit = &two }, same: true},
+		{name: "rollback", mutate: func(s *v1alpha1.RokkuSpec) { s.RollbackTo = &revision }, same: true},
+		{name: "image", mutate: func(s *v1alpha1.RokkuSpec) { s.Image = "wbaa/rokku:2" }, same: false},
+	}
+
+	baseHash, err := SpecHash(base)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := *base.DeepCopy()
+			tt.mutate(&spec)
+			hash, err := SpecHash(spec)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (hash == baseHash) != tt.same {
+				t.Errorf("hash equality = %v, want %v", hash == baseHash, tt.same)
+			}
+		})
+	}
+}
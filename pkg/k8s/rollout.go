@@ -0,0 +1,106 @@
+package k8s
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	appv1 "k8s.io/api/apps/v1"
+)
+
+const (
+	trackLabel  = "rokku.ing.com/track"
+	trackCanary = "canary"
+	colorLabel  = "rokku.ing.com/color"
+
+	// activeColorAnnotation records the color selected by the Rokku Service.
+	activeColorAnnotation = "rokku.ing.com/active-color"
+
+	// ColorBlue and ColorGreen are the colors of the Deployments used by the
+	// BlueGreen strategy.
+	ColorBlue  = "blue"
+	ColorGreen = "green"
+)
+
+// CanaryName returns the name of the canary Deployment for the given Rokku.
+func CanaryName(name string) string {
+	return name + "-canary"
+}
+
+// NewCanaryDeployment returns the Deployment running the given Rokku spec next
+// to the stable one. Its pods carry the same labels as the stable pods, so that
+// they're selected by the Rokku Service, plus a track label telling them apart.
+func NewCanaryDeployment(n *v1alpha1.Rokku, replicas int32) (*appv1.Deployment, error) {
+	deployment, err := NewDeployment(n)
+	if err != nil {
+		return nil, err
+	}
+	deployment.Name = CanaryName(n.Name)
+	deployment.Spec.Replicas = &replicas
+	deployment.Spec.Selector.MatchLabels = mergeMap(LabelsForRokku(n.Name), map[string]string{trackLabel: trackCanary})
+	deployment.Spec.Template.Labels[trackLabel] = trackCanary
+	return deployment, nil
+}
+
+// validateRollout checks the settings of the rollout strategies that are only
+// used once a rollout is in progress.
+func validateRollout(rollout *v1alpha1.RokkuRollout) error {
+	if rollout == nil || rollout.Canary == nil || rollout.Canary.Analysis == nil {
+		return nil
+	}
+	if rate := rollout.Canary.Analysis.MaxErrorRate; rate != "" {
+		value, err := strconv.ParseFloat(rate, 64)
+		if err != nil || value < 0 || value > 1 {
+			return validationErrorf("invalid canary maxErrorRate %q: must be a ratio between 0 and 1", rate)
+		}
+	}
+	return nil
+}
+
+// LabelsForRokkuColor returns the labels for the pods of the given color of a
+// Rokku CR with the given name.
+func LabelsForRokkuColor(name, color string) map[string]string {
+	return mergeMap(LabelsForRokku(name), map[string]string{colorLabel: color})
+}
+
+// ColorName returns the name of the Deployment of the given color.
+func ColorName(name, color string) string {
+	return name + "-" + color
+}
+
+// OtherColor returns the color a BlueGreen rollout moves to from color.
+func OtherColor(color string) string {
+	if color == ColorBlue {
+		return ColorGreen
+	}
+	return ColorBlue
+}
+
+// NewColorDeployment returns the Deployment of the given color used by the
+// BlueGreen strategy.
+func NewColorDeployment(n *v1alpha1.Rokku, color string) (*appv1.Deployment, error) {
+	deployment, err := NewDeployment(n)
+	if err != nil {
+		return nil, err
+	}
+	deployment.Name = ColorName(n.Name, color)
+	deployment.Spec.Selector.MatchLabels = LabelsForRokkuColor(n.Name, color)
+	deployment.Spec.Template.Labels[colorLabel] = color
+	return deployment, nil
+}
+
+// SpecHash returns a short hash identifying the given Rokku spec, ignoring the
+// number of replicas, the revision history settings and the maintenance mode.
+func SpecHash(spec v1alpha1.RokkuSpec) (string, error) {
+	spec.Replicas = nil
+	spec.Maintenance = nil
+	spec.RevisionHistoryLimit = nil
+	spec.RollbackTo = nil
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data))[:16], nil
+}
@@ -0,0 +1,162 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestNewCanaryDeployment(t *testing.T) {
+	rokku := newTestRokku()
+	rokku.Spec.PodTemplate.Ports = []corev1.ContainerPort{{Name: "http", ContainerPort: 80}}
+	rokku.Spec.PodTemplate.SecurityContext = &corev1.SecurityContext{
+		Capabilities: &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+	}
+
+	stable, err := NewDeployment(rokku)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	canary, err := NewCanaryDeployment(rokku, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if canary.Name != "rokku-canary" {
+		t.Errorf("canary name = %q, want rokku-canary", canary.Name)
+	}
+	if *canary.Spec.Replicas != 2 {
+		t.Errorf("canary replicas = %d, want 2", *canary.Spec.Replicas)
+	}
+	wantSelector := map[string]string{
+		"rokku.ing.com/resource-name": "rokku",
+		"rokku.ing.com/app":           "rokku",
+		trackLabel:                    trackCanary,
+	}
+	if !reflect.DeepEqual(canary.Spec.Selector.MatchLabels, wantSelector) {
+		t.Errorf("canary selector = %v, want %v", canary.Spec.Selector.MatchLabels, wantSelector)
+	}
+	if canary.Spec.Template.Labels[trackLabel] != trackCanary {
+		t.Errorf("canary pods must carry the %s label", trackLabel)
+	}
+	if _, found := stable.Spec.Template.Labels[trackLabel]; found {
+		t.Errorf("stable pods must not carry the %s label", trackLabel)
+	}
+
+	// Building the canary from the same Rokku must neither change the stable
+	// pods nor the Rokku spec
+	wantCapabilities := []corev1.Capability{"NET_BIND_SERVICE"}
+	for _, c := range []*corev1.Container{&stable.Spec.Template.Spec.Containers[0], &canary.Spec.Template.Spec.Containers[0]} {
+		if got := c.SecurityContext.Capabilities.Add; !reflect.DeepEqual(got, wantCapabilities) {
+			t.Errorf("added capabilities = %v, want %v", got, wantCapabilities)
+		}
+	}
+	if got := rokku.Spec.PodTemplate.SecurityContext.Capabilities.Add; got != nil {
+		t.Errorf("Rokku spec capabilities were mutated: %v", got)
+	}
+}
+
+func TestValidateRollout(t *testing.T) {
+	tests := []struct {
+		maxErrorRate string
+		wantErr      bool
+	}{
+		{maxErrorRate: ""},
+		{maxErrorRate: "0"},
+		{maxErrorRate: "0.01"},
+		{maxErrorRate: "1"},
+		{maxErrorRate: "5%", wantErr: true},
+		{maxErrorRate: "-0.1", wantErr: true},
+		{maxErrorRate: "1.5", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.maxErrorRate, func(t *testing.T) {
+			rokku := newTestRokku()
+			rokku.Spec.Rollout = &v1alpha1.RokkuRollout{
+				Strategy: v1alpha1.RolloutStrategyCanary,
+				Canary: &v1alpha1.RokkuCanary{
+					Analysis: &v1alpha1.RokkuCanaryAnalysis{PrometheusURL: "http://prometheus:9090", MaxErrorRate: tt.maxErrorRate},
+				},
+			}
+			err := ValidateRokku(rokku)
+			if _, ok := err.(*ValidationError); ok != tt.wantErr {
+				t.Errorf("ValidateRokku() = %v, want a ValidationError: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewColorDeployment(t *testing.T) {
+	for _, color := range []string{ColorBlue, ColorGreen} {
+		t.Run(color, func(t *testing.T) {
+			deploy, err := NewColorDeployment(newTestRokku(), color)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if want := "rokku-" + color; deploy.Name != want {
+				t.Errorf("name = %q, want %q", deploy.Name, want)
+			}
+			if want := LabelsForRokkuColor("rokku", color); !reflect.DeepEqual(deploy.Spec.Selector.MatchLabels, want) {
+				t.Errorf("selector = %v, want %v", deploy.Spec.Selector.MatchLabels, want)
+			}
+			if deploy.Spec.Template.Labels[colorLabel] != color {
+				t.Errorf("pods must carry the %s label", colorLabel)
+			}
+		})
+	}
+}
+
+func TestOtherColor(t *testing.T) {
+	tests := []struct {
+		color string
+		want  string
+	}{
+		{color: ColorBlue, want: ColorGreen},
+		{color: ColorGreen, want: ColorBlue},
+		{color: "", want: ColorBlue},
+	}
+	for _, tt := range tests {
+		if got := OtherColor(tt.color); got != tt.want {
+			t.Errorf("OtherColor(%q) = %q, want %q", tt.color, got, tt.want)
+		}
+	}
+}
+
+func TestNewServiceColorSelector(t *testing.T) {
+	tests := []struct {
+		name         string
+		strategy     v1alpha1.RolloutStrategy
+		activeColor  string
+		wantSelector map[string]string
+	}{
+		{name: "rolling update", strategy: v1alpha1.RolloutStrategyRollingUpdate, activeColor: ColorBlue, wantSelector: LabelsForRokku("rokku")},
+		{name: "no active color", strategy: v1alpha1.RolloutStrategyBlueGreen, wantSelector: LabelsForRokku("rokku")},
+		{name: "active color", strategy: v1alpha1.RolloutStrategyBlueGreen, activeColor: ColorGreen, wantSelector: LabelsForRokkuColor("rokku", ColorGreen)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rokku := newTestRokku()
+			rokku.Spec.Rollout = &v1alpha1.RokkuRollout{Strategy: tt.strategy}
+			rokku.Status.ActiveColor = tt.activeColor
+
+			svc, err := NewService(rokku)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(svc.Spec.Selector, tt.wantSelector) {
+				t.Errorf("selector = %v, want %v", svc.Spec.Selector, tt.wantSelector)
+			}
+			wantColor := ""
+			if tt.strategy == v1alpha1.RolloutStrategyBlueGreen {
+				wantColor = tt.activeColor
+			}
+			if got := GetActiveColorFromObject(svc); got != wantColor {
+				t.Errorf("active color annotation = %q, want %q", got, wantColor)
+			}
+		})
+	}
+}
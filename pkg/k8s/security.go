@@ -0,0 +1,223 @@
+package k8s
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// seccompPodAnnotation sets the seccomp profile of the pod. The
+	// securityContext.seccompProfile field isn't available in the Kubernetes
+	// API version the operator is built against, and would be dropped when
+	// sending the Deployment. From Kubernetes 1.19, the API server copies the
+	// annotation into the field of the pods created without it.
+	seccompPodAnnotation       = "seccomp.security.alpha.kubernetes.io/pod"
+	seccompContainerAnnotation = "container.seccomp.security.alpha.kubernetes.io/"
+	seccompRuntimeDefault      = "runtime/default"
+
+	rangerCachePath = "/etc/ranger/s3/policycache"
+
+	// The writable directories volumes are prefixed so that they don't
+	// collide with the pod template volumes
+	tmpVolumeName         = "rokku-tmp"
+	rangerCacheVolumeName = "rokku-ranger-cache"
+)
+
+// restrictedVolumeTypes are the volume types allowed by the restricted Pod
+// Security Standard.
+var restrictedVolumeTypes = []string{"configMap", "csi", "downwardAPI", "emptyDir", "persistentVolumeClaim", "projected", "secret"}
+
+// setupSecurityProfile hardens the pod and the containers generated by the
+// operator. It must run before the user provided containers are added.
+func setupSecurityProfile(profile v1alpha1.SecurityProfile, dep *appv1.Deployment) {
+	if profile != v1alpha1.SecurityProfileRestricted {
+		return
+	}
+
+	podSpec := &dep.Spec.Template.Spec
+	yes, no := true, false
+
+	if podSpec.SecurityContext == nil {
+		podSpec.SecurityContext = &corev1.PodSecurityContext{}
+	}
+	if podSpec.SecurityContext.RunAsNonRoot == nil {
+		podSpec.SecurityContext.RunAsNonRoot = &yes
+	}
+	if dep.Spec.Template.Annotations == nil {
+		dep.Spec.Template.Annotations = make(map[string]string)
+	}
+	if _, ok := dep.Spec.Template.Annotations[seccompPodAnnotation]; !ok {
+		dep.Spec.Template.Annotations[seccompPodAnnotation] = seccompRuntimeDefault
+	}
+
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for i := range containers {
+			c := &containers[i]
+			// The security context may be shared with the Rokku spec
+			sc := &corev1.SecurityContext{}
+			if c.SecurityContext != nil {
+				sc = c.SecurityContext.DeepCopy()
+			}
+			if sc.RunAsNonRoot == nil {
+				sc.RunAsNonRoot = &yes
+			}
+			if sc.ReadOnlyRootFilesystem == nil {
+				sc.ReadOnlyRootFilesystem = &yes
+			}
+			if sc.AllowPrivilegeEscalation == nil {
+				sc.AllowPrivilegeEscalation = &no
+			}
+			if sc.Capabilities == nil {
+				sc.Capabilities = &corev1.Capabilities{}
+			}
+			if !hasCapability(sc.Capabilities.Drop, "ALL") {
+				sc.Capabilities.Drop = append(sc.Capabilities.Drop, "ALL")
+			}
+			c.SecurityContext = sc
+		}
+	}
+
+	// The root filesystem is read-only, so Rokku writes its temporary files
+	// and the Ranger policy cache to emptyDirs
+	addWritableDir(dep, tmpVolumeName, "/tmp")
+	addWritableDir(dep, rangerCacheVolumeName, rangerCachePath)
+}
+
+func addWritableDir(dep *appv1.Deployment, name, path string) {
+	container := &dep.Spec.Template.Spec.Containers[0]
+	for _, m := range container.VolumeMounts {
+		if m.MountPath == path {
+			return
+		}
+	}
+	dep.Spec.Template.Spec.Volumes = append(dep.Spec.Template.Spec.Volumes, corev1.Volume{
+		Name:         name,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+	container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+		Name:      name,
+		MountPath: path,
+	})
+}
+
+// RestrictedViolations returns the violations of the restricted Pod Security
+// Standard found in the pod template.
+func RestrictedViolations(template *corev1.PodTemplateSpec) []string {
+	var violations []string
+	podSpec := &template.Spec
+
+	if podSpec.HostNetwork || podSpec.HostPID || podSpec.HostIPC {
+		violations = append(violations, "host namespaces must not be shared")
+	}
+
+	for _, v := range podSpec.Volumes {
+		if t := volumeType(v.VolumeSource); !containsString(restrictedVolumeTypes, t) {
+			violations = append(violations, fmt.Sprintf("volume %q must not be of type %s", v.Name, t))
+		}
+	}
+
+	podNonRoot := false
+	var podUser *int64
+	if podSpec.SecurityContext != nil {
+		podNonRoot = podSpec.SecurityContext.RunAsNonRoot != nil && *podSpec.SecurityContext.RunAsNonRoot
+		podUser = podSpec.SecurityContext.RunAsUser
+	}
+	if podUser != nil && *podUser == 0 {
+		violations = append(violations, "pod must not run as user 0")
+	}
+	if !isRuntimeDefaultSeccomp(template.Annotations[seccompPodAnnotation]) {
+		violations = append(violations, "pod must use the runtime/default or a localhost seccomp profile")
+	}
+
+	var containers []corev1.Container
+	containers = append(containers, podSpec.InitContainers...)
+	containers = append(containers, podSpec.Containers...)
+	for _, c := range containers {
+		if profile, ok := template.Annotations[seccompContainerAnnotation+c.Name]; ok && !isRuntimeDefaultSeccomp(profile) {
+			violations = append(violations, fmt.Sprintf("container %q must use the runtime/default or a localhost seccomp profile", c.Name))
+		}
+		for _, p := range c.Ports {
+			if p.HostPort != 0 {
+				violations = append(violations, fmt.Sprintf("container %q must not use host ports", c.Name))
+				break
+			}
+		}
+
+		sc := c.SecurityContext
+		if sc == nil {
+			sc = &corev1.SecurityContext{}
+		}
+		if sc.Privileged != nil && *sc.Privileged {
+			violations = append(violations, fmt.Sprintf("container %q must not be privileged", c.Name))
+		}
+		if sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+			violations = append(violations, fmt.Sprintf("container %q must set allowPrivilegeEscalation to false", c.Name))
+		}
+		if sc.RunAsNonRoot != nil && !*sc.RunAsNonRoot || sc.RunAsNonRoot == nil && !podNonRoot {
+			violations = append(violations, fmt.Sprintf("container %q must set runAsNonRoot to true", c.Name))
+		}
+		if sc.RunAsUser != nil && *sc.RunAsUser == 0 {
+			violations = append(violations, fmt.Sprintf("container %q must not run as user 0", c.Name))
+		}
+		if sc.Capabilities == nil || !hasCapability(sc.Capabilities.Drop, "ALL") {
+			violations = append(violations, fmt.Sprintf("container %q must drop ALL capabilities", c.Name))
+		}
+		if sc.Capabilities != nil {
+			for _, capability := range sc.Capabilities.Add {
+				if capability != "NET_BIND_SERVICE" {
+					violations = append(violations, fmt.Sprintf("container %q must not add capability %s", c.Name, capability))
+				}
+			}
+		}
+	}
+
+	return violations
+}
+
+func isRuntimeDefaultSeccomp(profile string) bool {
+	return profile == seccompRuntimeDefault || profile == "docker/default" || strings.HasPrefix(profile, "localhost/")
+}
+
+func hasCapability(capabilities []corev1.Capability, capability corev1.Capability) bool {
+	for _, c := range capabilities {
+		if strings.EqualFold(string(c), string(capability)) {
+			return true
+		}
+	}
+	return false
+}
+
+func volumeType(source corev1.VolumeSource) string {
+	switch {
+	case source.ConfigMap != nil:
+		return "configMap"
+	case source.CSI != nil:
+		return "csi"
+	case source.DownwardAPI != nil:
+		return "downwardAPI"
+	case source.EmptyDir != nil:
+		return "emptyDir"
+	case source.PersistentVolumeClaim != nil:
+		return "persistentVolumeClaim"
+	case source.Projected != nil:
+		return "projected"
+	case source.Secret != nil:
+		return "secret"
+	case source.HostPath != nil:
+		return "hostPath"
+	}
+	return "other"
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestSetupSecurityProfile(t *testing.T) {
+	rokku := newTestRokku()
+	rokku.Spec.SecurityProfile = v1alpha1.SecurityProfileRestricted
+
+	dep, err := NewDeployment(rokku)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	podSpec := dep.Spec.Template.Spec
+	if sc := podSpec.SecurityContext; sc == nil || sc.RunAsNonRoot == nil || !*sc.RunAsNonRoot {
+		t.Errorf("pod security context = %+v, want runAsNonRoot", sc)
+	}
+	if profile := dep.Spec.Template.Annotations[seccompPodAnnotation]; profile != seccompRuntimeDefault {
+		t.Errorf("seccomp profile = %q, want %s", profile, seccompRuntimeDefault)
+	}
+	sc := podSpec.Containers[0].SecurityContext
+	if sc == nil || !*sc.ReadOnlyRootFilesystem || *sc.AllowPrivilegeEscalation || !hasCapability(sc.Capabilities.Drop, "ALL") {
+		t.Errorf("rokku container security context = %+v, want it hardened", sc)
+	}
+
+	mounts := make(map[string]string)
+	for _, m := range podSpec.Containers[0].VolumeMounts {
+		mounts[m.MountPath] = m.Name
+	}
+	for path, name := range map[string]string{"/tmp": tmpVolumeName, rangerCachePath: rangerCacheVolumeName} {
+		if mounts[path] != name {
+			t.Errorf("%s mounted from %q, want the %s emptyDir", path, mounts[path], name)
+		}
+	}
+
+	if violations := RestrictedViolations(&dep.Spec.Template); len(violations) != 0 {
+		t.Errorf("violations = %v, want none", violations)
+	}
+}
+
+func TestRestrictedViolations(t *testing.T) {
+	rokku := newTestRokku()
+	rokku.Spec.SecurityProfile = v1alpha1.SecurityProfileRestricted
+	rokku.Spec.Sidecars = []corev1.Container{{Name: "log-shipper"}}
+	rokku.Spec.PodTemplate.HostNetwork = true
+	rokku.Spec.PodTemplate.Volumes = []corev1.Volume{
+		{Name: "logs", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/log"}}},
+	}
+
+	dep, err := NewDeployment(rokku)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{
+		"host namespaces must not be shared",
+		`volume "logs" must not be of type hostPath`,
+		`container "log-shipper" must set allowPrivilegeEscalation to false`,
+		`container "log-shipper" must drop ALL capabilities`,
+	}
+	if got := RestrictedViolations(&dep.Spec.Template); !reflect.DeepEqual(got, want) {
+		t.Errorf("violations = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,128 @@
+package k8s
+
+import (
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ServiceAccountName returns the name of the ServiceAccount the Rokku pods
+// run as.
+func ServiceAccountName(n *v1alpha1.Rokku) string {
+	if n.Spec.PodTemplate.ServiceAccountName != "" {
+		return n.Spec.PodTemplate.ServiceAccountName
+	}
+	return n.Name + "-rokku"
+}
+
+// IsServiceAccountManaged tells whether the ServiceAccount of the Rokku pods
+// is created by the operator.
+func IsServiceAccountManaged(n *v1alpha1.Rokku) bool {
+	return n.Spec.PodTemplate.ServiceAccountName == ""
+}
+
+// RoleName returns the name of the Role created from
+// spec.serviceAccount.rules.
+func RoleName(name string) string {
+	return name + "-rokku"
+}
+
+// RoleBindingName returns the name of the RoleBinding granting the Rokku
+// ServiceAccount its permissions.
+func RoleBindingName(name string) string {
+	return name + "-rokku"
+}
+
+// HasRole tells whether a Role is created for the Rokku ServiceAccount.
+func HasRole(n *v1alpha1.Rokku) bool {
+	return n.Spec.ServiceAccount != nil && len(n.Spec.ServiceAccount.Rules) > 0
+}
+
+// HasRoleBinding tells whether a RoleBinding is created for the Rokku
+// ServiceAccount.
+func HasRoleBinding(n *v1alpha1.Rokku) bool {
+	return HasRole(n) || (n.Spec.ServiceAccount != nil && n.Spec.ServiceAccount.RoleRef != nil)
+}
+
+func automountServiceAccountToken(n *v1alpha1.Rokku) *bool {
+	automount := false
+	if n.Spec.ServiceAccount != nil && n.Spec.ServiceAccount.AutomountServiceAccountToken != nil {
+		automount = *n.Spec.ServiceAccount.AutomountServiceAccountToken
+	}
+	return &automount
+}
+
+// NewServiceAccount returns the ServiceAccount the Rokku pods run as.
+func NewServiceAccount(n *v1alpha1.Rokku) *corev1.ServiceAccount {
+	var annotations map[string]string
+	if n.Spec.ServiceAccount != nil {
+		annotations = n.Spec.ServiceAccount.Annotations
+	}
+	return &corev1.ServiceAccount{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ServiceAccount",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            ServiceAccountName(n),
+			Namespace:       n.Namespace,
+			OwnerReferences: ownerReferences(n),
+			Labels:          LabelsForRokku(n.Name),
+			Annotations:     annotations,
+		},
+		AutomountServiceAccountToken: automountServiceAccountToken(n),
+	}
+}
+
+// NewRole returns the Role holding the permissions listed by
+// spec.serviceAccount.rules.
+func NewRole(n *v1alpha1.Rokku) *rbacv1.Role {
+	return &rbacv1.Role{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Role",
+			APIVersion: "rbac.authorization.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            RoleName(n.Name),
+			Namespace:       n.Namespace,
+			OwnerReferences: ownerReferences(n),
+			Labels:          LabelsForRokku(n.Name),
+		},
+		Rules: n.Spec.ServiceAccount.Rules,
+	}
+}
+
+// NewRoleBinding returns the RoleBinding granting the Role created from
+// spec.serviceAccount.rules, or else the role referenced by
+// spec.serviceAccount.roleRef, to the Rokku ServiceAccount.
+func NewRoleBinding(n *v1alpha1.Rokku) *rbacv1.RoleBinding {
+	ref := v1alpha1.RokkuRoleRef{Kind: "Role", Name: RoleName(n.Name)}
+	if !HasRole(n) {
+		ref = *n.Spec.ServiceAccount.RoleRef
+	}
+	return &rbacv1.RoleBinding{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "RoleBinding",
+			APIVersion: "rbac.authorization.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            RoleBindingName(n.Name),
+			Namespace:       n.Namespace,
+			OwnerReferences: ownerReferences(n),
+			Labels:          LabelsForRokku(n.Name),
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     valueOrDefault(ref.Kind, "Role"),
+			Name:     ref.Name,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      ServiceAccountName(n),
+				Namespace: n.Namespace,
+			},
+		},
+	}
+}
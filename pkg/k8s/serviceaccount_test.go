@@ -0,0 +1,34 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	rbacv1 "k8s.io/api/rbac/v1"
+)
+
+func TestValidateServiceAccount(t *testing.T) {
+	rules := []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"configmaps"}, Verbs: []string{"get"}}}
+	tests := []struct {
+		name    string
+		sa      *v1alpha1.RokkuServiceAccount
+		wantErr bool
+	}{
+		{name: "rules", sa: &v1alpha1.RokkuServiceAccount{Rules: rules}},
+		{name: "roleRef", sa: &v1alpha1.RokkuServiceAccount{RoleRef: &v1alpha1.RokkuRoleRef{Kind: "ClusterRole", Name: "view"}}},
+		{name: "invalid roleRef kind", sa: &v1alpha1.RokkuServiceAccount{RoleRef: &v1alpha1.RokkuRoleRef{Kind: "Group", Name: "view"}}, wantErr: true},
+		{name: "roleRef and rules", sa: &v1alpha1.RokkuServiceAccount{RoleRef: &v1alpha1.RokkuRoleRef{Name: "view"}, Rules: rules}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rokku := newTestRokku()
+			rokku.Spec.ServiceAccount = tt.sa
+
+			err := ValidateRokku(rokku)
+			if _, ok := err.(*ValidationError); ok != tt.wantErr {
+				t.Errorf("ValidateRokku() = %v, want a ValidationError: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package k8s
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	defaultSTSImage    = "wbaa/rokku-sts"
+	defaultSTSPort     = int32(12345)
+	defaultSTSPortName = "http"
+	stsContainerName   = "rokku-sts"
+	stsDatabaseURLKey  = "url"
+	stsDatabaseUserKey = "username"
+	stsDatabasePassKey = "password"
+	stsMasterKeyKey    = "masterKey"
+)
+
+// STSName returns the name of the managed rokku-sts objects for the given
+// Rokku.
+func STSName(name string) string {
+	return name + "-sts"
+}
+
+// LabelsForSTS returns the labels of the managed rokku-sts pods. They don't
+// match LabelsForRokku so that rokku-sts is neither counted as a Rokku pod nor
+// selected by the Rokku Service.
+func LabelsForSTS(name string) map[string]string {
+	return map[string]string{
+		"rokku.ing.com/resource-name": name,
+		"rokku.ing.com/app":           "rokku-sts",
+	}
+}
+
+// IsSTSManaged tells whether rokku-sts is deployed by the operator.
+func IsSTSManaged(n *v1alpha1.Rokku) bool {
+	return n.Spec.STS != nil && n.Spec.STS.Managed != nil
+}
+
+func stsPort(sts *v1alpha1.RokkuManagedSTS) int32 {
+	if sts.Port != 0 {
+		return sts.Port
+	}
+	return defaultSTSPort
+}
+
+// managedSTSURI returns the URI of the Service of the managed rokku-sts.
+func managedSTSURI(n *v1alpha1.Rokku) string {
+	return fmt.Sprintf("http://%s:%d", ServiceDNSName(STSName(n.Name), n.Namespace), stsPort(n.Spec.STS.Managed))
+}
+
+// NewSTSService returns the Service of the managed rokku-sts.
+func NewSTSService(n *v1alpha1.Rokku) *corev1.Service {
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            STSName(n.Name),
+			Namespace:       n.Namespace,
+			OwnerReferences: ownerReferences(n),
+			Labels:          LabelsForSTS(n.Name),
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Name:       defaultSTSPortName,
+					Protocol:   corev1.ProtocolTCP,
+					TargetPort: intstr.FromString(defaultSTSPortName),
+					Port:       stsPort(n.Spec.STS.Managed),
+				},
+			},
+			Selector: LabelsForSTS(n.Name),
+		},
+	}
+}
+
+// NewSTSDeployment returns the Deployment of the managed rokku-sts. It's
+// annotated with the checksum of its spec, covering the fields inherited from
+// the Rokku pod template, which tells whether the Deployment is up to date.
+func NewSTSDeployment(n *v1alpha1.Rokku) (*appv1.Deployment, error) {
+	sts := n.Spec.STS.Managed
+	replicas := int32(1)
+	if sts.Replicas != nil {
+		replicas = *sts.Replicas
+	}
+
+	port := stsPort(sts)
+	automount := false
+	dep := &appv1.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Deployment",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            STSName(n.Name),
+			Namespace:       n.Namespace,
+			OwnerReferences: ownerReferences(n),
+			Labels:          LabelsForSTS(n.Name),
+		},
+		Spec: appv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: LabelsForSTS(n.Name),
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: LabelsForSTS(n.Name),
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:      stsContainerName,
+							Image:     valueOrDefault(sts.Image, defaultSTSImage),
+							Resources: sts.Resources,
+							Ports: []corev1.ContainerPort{
+								{Name: defaultSTSPortName, ContainerPort: port, Protocol: corev1.ProtocolTCP},
+							},
+							Env: append(stsEnv(n.Spec.STS, port), sts.Env...),
+							ReadinessProbe: &corev1.Probe{
+								Handler: corev1.Handler{
+									TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString(defaultSTSPortName)},
+								},
+								TimeoutSeconds: defaultProbeTimeoutSeconds,
+							},
+						},
+					},
+					NodeSelector:                 n.Spec.PodTemplate.NodeSelector,
+					Tolerations:                  n.Spec.PodTemplate.Tolerations,
+					ImagePullSecrets:             n.Spec.PodTemplate.ImagePullSecrets,
+					AutomountServiceAccountToken: &automount,
+				},
+			},
+		},
+	}
+	if err := setSpecChecksum(dep); err != nil {
+		return nil, err
+	}
+	return dep, nil
+}
+
+func stsEnv(spec *v1alpha1.RokkuSTS, port int32) []corev1.EnvVar {
+	sts := spec.Managed
+	env := []corev1.EnvVar{
+		{Name: "STS_HOST", Value: "0.0.0.0"},
+		{Name: "STS_PORT", Value: strconv.Itoa(int(port))},
+	}
+	env = append(env, oidcEnv(spec.OIDC)...)
+	if sts.DatabaseSecret != nil {
+		env = append(env,
+			secretEnv("MARIADB_URL", *sts.DatabaseSecret, stsDatabaseURLKey),
+			secretEnv("MARIADB_USERNAME", *sts.DatabaseSecret, stsDatabaseUserKey),
+			secretEnv("MARIADB_PASSWORD", *sts.DatabaseSecret, stsDatabasePassKey),
+		)
+	}
+	if sts.MasterKeySecret != nil {
+		env = append(env, secretEnv("STS_MASTER_KEY", *sts.MasterKeySecret, stsMasterKeyKey))
+	}
+	return env
+}
+
+func secretEnv(name string, secret corev1.LocalObjectReference, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: secret,
+				Key:                  key,
+			},
+		},
+	}
+}
@@ -0,0 +1,75 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func newSTSTestRokku() *v1alpha1.Rokku {
+	rokku := newTestRokku()
+	rokku.Spec.STS = &v1alpha1.RokkuSTS{Managed: &v1alpha1.RokkuManagedSTS{}}
+	return rokku
+}
+
+func TestNewSTSDeploymentChecksum(t *testing.T) {
+	checksum := func(t *testing.T, rokku *v1alpha1.Rokku) string {
+		dep, err := NewSTSDeployment(rokku)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return GetConfigChecksumFromObject(dep)
+	}
+	base := checksum(t, newSTSTestRokku())
+	if base == "" {
+		t.Fatal("expected a checksum")
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(rokku *v1alpha1.Rokku)
+	}{
+		{name: "sts image", mutate: func(rokku *v1alpha1.Rokku) { rokku.Spec.STS.Managed.Image = "wbaa/rokku-sts:2" }},
+		{name: "node selector", mutate: func(rokku *v1alpha1.Rokku) {
+			rokku.Spec.PodTemplate.NodeSelector = map[string]string{"zone": "a"}
+		}},
+		{name: "tolerations", mutate: func(rokku *v1alpha1.Rokku) {
+			rokku.Spec.PodTemplate.Tolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}}
+		}},
+		{name: "image pull secrets", mutate: func(rokku *v1alpha1.Rokku) {
+			rokku.Spec.PodTemplate.ImagePullSecrets = []corev1.LocalObjectReference{{Name: "registry"}}
+		}},
+	}
+	for _, tt := range tests {
+		rokku := newSTSTestRokku()
+		tt.mutate(rokku)
+		if checksum(t, rokku) == base {
+			t.Errorf("%s: checksum unchanged", tt.name)
+		}
+	}
+}
+
+func TestValidateManagedSTS(t *testing.T) {
+	tests := []struct {
+		name    string
+		sts     string
+		wantErr bool
+	}{
+		{name: "defaulted"},
+		{name: "backends.sts", sts: "http://rokku-sts:8080", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rokku := newSTSTestRokku()
+			rokku.Spec.Backends = &v1alpha1.RokkuBackends{STS: tt.sts}
+			SetRokkuDefaults(rokku)
+
+			err := ValidateRokku(rokku)
+			if _, ok := err.(*ValidationError); ok != tt.wantErr {
+				t.Errorf("ValidateRokku() = %v, want a ValidationError: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package k8s
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	truststoreContainerName = "truststore"
+	truststoreVolumeName    = "truststore"
+	caBundlesVolumeName     = "ca-bundles"
+	truststoreMountPath     = "/etc/rokku/truststore"
+	caBundlesMountPath      = "/etc/rokku/ca-bundles"
+	truststorePath          = truststoreMountPath + "/truststore.p12"
+	trustChecksumAnnotation = "rokku.ing.com/trust-checksum"
+	jvmCACertsPassword      = "changeit"
+
+	// truststorePassword is only there because keytool requires one. The
+	// truststore holds public certificates only and lives in an emptyDir
+	// private to the pod, the password provides no integrity guarantee.
+	truststorePassword = "changeit"
+)
+
+// truststoreScript builds a PKCS12 truststore holding the JVM default CA
+// certificates plus every certificate of the mounted CA bundles. keytool only
+// imports the first certificate of a PEM file, so the bundles are split
+// first.
+var truststoreScript = strings.Join([]string{
+	"set -e",
+	"store=" + truststorePath,
+	"work=" + truststoreMountPath + "/certs",
+	`rm -rf "$store" "$work" && mkdir -p "$work"`,
+	`cacerts=$(find -L "${JAVA_HOME:-/usr/lib/jvm}" -name cacerts -type f | head -n 1)`,
+	`keytool -importkeystore -noprompt -srckeystore "$cacerts" -srcstorepass ` + jvmCACertsPassword +
+		` -destkeystore "$store" -deststoretype PKCS12 -deststorepass ` + truststorePassword,
+	`for bundle in ` + caBundlesMountPath + `/*; do`,
+	`  awk -v out="$work/$(basename "$bundle" .pem)" '/-----BEGIN CERTIFICATE-----/ {n++} n > 0 {print > (out "-" n ".pem")}' "$bundle"`,
+	`done`,
+	`for cert in "$work"/*.pem; do`,
+	`  [ -e "$cert" ] || continue`,
+	`  keytool -importcert -noprompt -alias "$(basename "$cert" .pem)" -file "$cert" -keystore "$store" -storetype PKCS12 -storepass ` + truststorePassword,
+	`done`,
+	`rm -rf "$work"`,
+}, "\n")
+
+// SetTrustChecksum records the checksum of the CA bundles content into the
+// pod template annotations, so that the pods are replaced whenever a bundle
+// changes.
+func SetTrustChecksum(n *v1alpha1.Rokku, checksum string) {
+	if n.Spec.PodTemplate.Annotations == nil {
+		n.Spec.PodTemplate.Annotations = make(map[string]string)
+	}
+	n.Spec.PodTemplate.Annotations[trustChecksumAnnotation] = checksum
+}
+
+func hasCABundles(spec v1alpha1.RokkuSpec) bool {
+	return spec.Trust != nil && len(spec.Trust.CABundles) > 0
+}
+
+// setupTrust adds the init container building the truststore from the CA
+// bundles and mounts it into the rokku container.
+func setupTrust(spec v1alpha1.RokkuSpec, dep *appv1.Deployment) error {
+	if !hasCABundles(spec) {
+		return nil
+	}
+
+	var sources []corev1.VolumeProjection
+	for i, bundle := range spec.Trust.CABundles {
+		path := fmt.Sprintf("bundle-%d.pem", i)
+		switch {
+		case bundle.ConfigMap != nil && bundle.Secret == nil:
+			sources = append(sources, corev1.VolumeProjection{
+				ConfigMap: &corev1.ConfigMapProjection{
+					LocalObjectReference: bundle.ConfigMap.LocalObjectReference,
+					Items:                []corev1.KeyToPath{{Key: bundle.ConfigMap.Key, Path: path}},
+					Optional:             bundle.ConfigMap.Optional,
+				},
+			})
+		case bundle.Secret != nil && bundle.ConfigMap == nil:
+			sources = append(sources, corev1.VolumeProjection{
+				Secret: &corev1.SecretProjection{
+					LocalObjectReference: bundle.Secret.LocalObjectReference,
+					Items:                []corev1.KeyToPath{{Key: bundle.Secret.Key, Path: path}},
+					Optional:             bundle.Secret.Optional,
+				},
+			})
+		default:
+			return validationErrorf("exactly one of configMap or secret must be set in CA bundle %d", i)
+		}
+	}
+
+	podSpec := &dep.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes,
+		corev1.Volume{
+			Name:         caBundlesVolumeName,
+			VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{Sources: sources}},
+		},
+		corev1.Volume{
+			Name:         truststoreVolumeName,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		},
+	)
+	podSpec.InitContainers = append(podSpec.InitContainers, corev1.Container{
+		Name:    truststoreContainerName,
+		Image:   spec.Image,
+		Command: []string{"/bin/sh", "-c", truststoreScript},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: caBundlesVolumeName, MountPath: caBundlesMountPath, ReadOnly: true},
+			{Name: truststoreVolumeName, MountPath: truststoreMountPath},
+		},
+	})
+	podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      truststoreVolumeName,
+		MountPath: truststoreMountPath,
+		ReadOnly:  true,
+	})
+	return nil
+}
+
+// trustJavaOpts points the JVM to the truststore built from the CA bundles.
+func trustJavaOpts(spec v1alpha1.RokkuSpec) []string {
+	if !hasCABundles(spec) {
+		return nil
+	}
+	return []string{
+		"-Djavax.net.ssl.trustStore=" + truststorePath,
+		"-Djavax.net.ssl.trustStoreType=PKCS12",
+		"-Djavax.net.ssl.trustStorePassword=" + truststorePassword,
+	}
+}
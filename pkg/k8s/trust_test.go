@@ -0,0 +1,136 @@
+package k8s
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+	appv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func newTrustTestDeployment() *appv1.Deployment {
+	return &appv1.Deployment{
+		Spec: appv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "rokku"}}},
+			},
+		},
+	}
+}
+
+func TestSetupTrust(t *testing.T) {
+	configMapBundle := v1alpha1.RokkuCABundle{
+		ConfigMap: &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "ca"},
+			Key:                  "ca.pem",
+		},
+	}
+	secretBundle := v1alpha1.RokkuCABundle{
+		Secret: &corev1.SecretKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "ca"},
+			Key:                  "tls.crt",
+		},
+	}
+
+	tests := []struct {
+		name        string
+		trust       *v1alpha1.RokkuTrust
+		wantSources []corev1.VolumeProjection
+		wantErr     bool
+	}{
+		{
+			name: "no trust",
+		},
+		{
+			name:  "no bundles",
+			trust: &v1alpha1.RokkuTrust{},
+		},
+		{
+			name:  "configMap and secret bundles",
+			trust: &v1alpha1.RokkuTrust{CABundles: []v1alpha1.RokkuCABundle{configMapBundle, secretBundle}},
+			wantSources: []corev1.VolumeProjection{
+				{ConfigMap: &corev1.ConfigMapProjection{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "ca"},
+					Items:                []corev1.KeyToPath{{Key: "ca.pem", Path: "bundle-0.pem"}},
+				}},
+				{Secret: &corev1.SecretProjection{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "ca"},
+					Items:                []corev1.KeyToPath{{Key: "tls.crt", Path: "bundle-1.pem"}},
+				}},
+			},
+		},
+		{
+			name: "both configMap and secret",
+			trust: &v1alpha1.RokkuTrust{CABundles: []v1alpha1.RokkuCABundle{
+				{ConfigMap: configMapBundle.ConfigMap, Secret: secretBundle.Secret},
+			}},
+			wantErr: true,
+		},
+		{
+			name:    "neither configMap nor secret",
+			trust:   &v1alpha1.RokkuTrust{CABundles: []v1alpha1.RokkuCABundle{{}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := v1alpha1.RokkuSpec{Image: "rokku:latest", Trust: tt.trust}
+			dep := newTrustTestDeployment()
+
+			err := setupTrust(spec, dep)
+			if tt.wantErr {
+				if _, ok := err.(*ValidationError); !ok {
+					t.Fatalf("expected a ValidationError, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			podSpec := dep.Spec.Template.Spec
+			if tt.wantSources == nil {
+				if !reflect.DeepEqual(dep, newTrustTestDeployment()) {
+					t.Errorf("deployment must not be changed without CA bundles, got %+v", podSpec)
+				}
+				if opts := trustJavaOpts(spec); opts != nil {
+					t.Errorf("trustJavaOpts() = %v, want none", opts)
+				}
+				return
+			}
+
+			if len(podSpec.Volumes) != 2 {
+				t.Fatalf("got %d volumes, want 2", len(podSpec.Volumes))
+			}
+			if got := podSpec.Volumes[0].Projected; got == nil || !reflect.DeepEqual(got.Sources, tt.wantSources) {
+				t.Errorf("CA bundles volume = %+v, want sources %+v", podSpec.Volumes[0], tt.wantSources)
+			}
+			if podSpec.Volumes[1].EmptyDir == nil {
+				t.Errorf("truststore volume = %+v, want an emptyDir", podSpec.Volumes[1])
+			}
+
+			if len(podSpec.InitContainers) != 1 {
+				t.Fatalf("got %d init containers, want 1", len(podSpec.InitContainers))
+			}
+			if init := podSpec.InitContainers[0]; init.Name != truststoreContainerName || init.Image != spec.Image {
+				t.Errorf("init container = %s (%s), want %s (%s)", init.Name, init.Image, truststoreContainerName, spec.Image)
+			}
+
+			wantMounts := []corev1.VolumeMount{{Name: truststoreVolumeName, MountPath: truststoreMountPath, ReadOnly: true}}
+			if mounts := podSpec.Containers[0].VolumeMounts; !reflect.DeepEqual(mounts, wantMounts) {
+				t.Errorf("rokku container mounts = %+v, want %+v", mounts, wantMounts)
+			}
+
+			wantOpts := []string{
+				"-Djavax.net.ssl.trustStore=/etc/rokku/truststore/truststore.p12",
+				"-Djavax.net.ssl.trustStoreType=PKCS12",
+				"-Djavax.net.ssl.trustStorePassword=changeit",
+			}
+			if opts := trustJavaOpts(spec); !reflect.DeepEqual(opts, wantOpts) {
+				t.Errorf("trustJavaOpts() = %v, want %v", opts, wantOpts)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package k8s
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/jwi078/rokku-operator/pkg/apis/rokku/v1alpha1"
+)
+
+// ValidationError tells that the Rokku spec is invalid. Unlike the other
+// errors returned by the builders, retrying doesn't help until the spec is
+// changed.
+type ValidationError struct {
+	message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.message
+}
+
+func validationErrorf(format string, args ...interface{}) error {
+	return &ValidationError{message: fmt.Sprintf(format, args...)}
+}
+
+// ValidateRokku returns a ValidationError when the objects generated for the
+// given Rokku can't be assembled because of its spec.
+func ValidateRokku(n *v1alpha1.Rokku) error {
+	if sa := n.Spec.ServiceAccount; sa != nil && sa.RoleRef != nil {
+		if kind := sa.RoleRef.Kind; kind != "" && kind != "Role" && kind != "ClusterRole" {
+			return validationErrorf("invalid roleRef kind %q: must be Role or ClusterRole", kind)
+		}
+		if len(sa.Rules) > 0 {
+			return validationErrorf("only one of roleRef or rules can be set in serviceAccount")
+		}
+	}
+
+	if proxy := n.Spec.Proxy; proxy != nil && proxy.ServiceCIDR != "" {
+		if _, _, err := net.ParseCIDR(proxy.ServiceCIDR); err != nil {
+			return validationErrorf("invalid proxy serviceCIDR %q: %v", proxy.ServiceCIDR, err)
+		}
+	}
+
+	// The defaults point backends.sts to the managed rokku-sts
+	if b := n.Spec.Backends; IsSTSManaged(n) && b != nil && b.STS != "" && b.STS != managedSTSURI(n) {
+		return validationErrorf("backends.sts can't be set along with a managed rokku-sts")
+	}
+
+	if err := validateRollout(n.Spec.Rollout); err != nil {
+		return err
+	}
+
+	n = n.DeepCopy()
+	_, err := NewService(n)
+	if err == nil {
+		_, err = NewDeployment(n)
+	}
+	if err == nil && n.Spec.Monitoring != nil && n.Spec.Monitoring.Alerts != nil {
+		_, err = NewPrometheusRule(n)
+	}
+	if verr, ok := err.(*ValidationError); ok {
+		return verr
+	}
+	return nil
+}